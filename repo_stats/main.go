@@ -1,65 +1,224 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"repo_stats/server"
 	"repo_stats/services"
 	"repo_stats/utils"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
 	var err error
 
-	envFile, err := os.Open(".env")
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		err = runDiff(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "slack" {
+		err = runSlack(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "webhook-replay" {
+		err = runWebhookReplay(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err = runServe(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	repoUserFlag := flag.String("owner", "", "repository owner, prompted for if empty")
+	repoNameFlag := flag.String("repo", "", "repository name, prompted for if empty")
+	format := flag.String("format", "text", "output format: text, json, markdown, html or csv")
+	outPath := flag.String("out", "", "file to write json, markdown or html output to, defaults to stdout, or directory for csv")
+	depth := flag.Int("depth", 2, "number of directory levels to roll up")
+	showRenames := flag.Bool("renames", false, "output the rename history of renamed files")
+	existingOnly := flag.Bool("existing-only", false, "only rank changes to files still in the repository")
+	cardsDir := flag.String("cards", "", "directory to write SVG and PNG highlight cards to")
+	cardColors := flag.String("card-colors", "", "card colors as role=#rrggbb pairs, ex: background=#000000,accent=#ff8800")
+	interactive := flag.Bool("interactive", false, "browse the full results in the terminal after collection")
+	noColor := flag.Bool("no-color", false, "disable colors, same as setting NO_COLOR")
+	colorModeName := flag.String("color", "auto", "color mode: auto, truecolor, 256, 16 or none")
+	themeName := flag.String("theme", "default", "color theme name (default, light, solarized, high-contrast) or JSON theme file")
+	cachePath := flag.String("cache", "", "file to keep collected data in, later runs only fetch new activity")
+	teamsPath := flag.String("teams", "", "JSON file mapping team names to contributors, to aggregate stats per team")
+	reposFlag := flag.String("repos", "", "comma separated owner/repo entries to compare on a leaderboard")
+	reposFile := flag.String("repos-file", "", "file of owner/repo entries, one per line, to compare on a leaderboard")
+	snapshotPath := flag.String("save-snapshot", "", "file to save the full results to, for use with the diff command")
+	recordDir := flag.String("record", "", "directory to save every GitHub API response to, for use with --replay")
+	replayDir := flag.String("replay", "", "directory of responses saved with --record to use instead of the network")
+	hostFlags := addHostFlags(flag.CommandLine)
+	tokenFlags := addTokenFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Checked before anything is collected, an unknown format would only fail after every request
+	err = checkFormat(*format, "text", "json", "markdown", "html", "csv")
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
-	envData, err := utils.ReadEnv(envFile)
+	if *format != "text" && *format != "csv" && *outPath == "" {
+		// Keep stdout clean for structured output
+		utils.SetOutput(os.Stderr)
+	}
+
+	err = setupColors(*colorModeName, *noColor, *themeName)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 
-	repoUser := utils.GetInput("Repository Owner", utils.Title)
-	repoName := utils.GetInput("Repository Name", utils.Title)
+	cardTheme, err := utils.ParseCardTheme(*cardColors)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
 
-	// Make stuff
-	api := services.NewGHAPI(repoUser, repoName, envData["GITHUB_TOKEN"])
-	stats := utils.NewStats(repoUser, repoName,
-		[]string{".png", ".svg", ".jpg", ".lock", ".json", ".log", ".md", ".yml", ".pdf"},
-		[]string{"package-lock.json", "yarn.lock", "package.json"},
-		[]string{".github", ".git", ".husky", "client/docs", "client/node_modules", "client/patches",
-			"server/node_modules"},
-	)
+	err = setupTransport(*recordDir, *replayDir)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
 
-	//	Get PRs
-	prs, err := api.GetPRs()
+	// A repository given as a full URL is on the instance of its host
+	repoUser, repoName := *repoUserFlag, *repoNameFlag
+	var location *repoLocation
+	if strings.Contains(repoName, "://") {
+		location, err = parseRepoLocation(repoName, *hostFlags.provider)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+		repoUser, repoName = location.owner, location.name
+	}
+	repos, locations, err := repoList(*reposFlag, *reposFile, *hostFlags.provider)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
-	stats.SetPRs(prs)
+	if location == nil {
+		location = firstLocation(repos, locations)
+	}
 
-	// Get Commits
-	commits, err := api.GetCommits()
+	options := collectOptions{depth: *depth, showRenames: *showRenames, existingOnly: *existingOnly,
+		cachePath: *cachePath}
+	err = hostFlags.apply(&options, location)
+	if err == nil {
+		err = options.setLocations(locations)
+	}
 	if err != nil {
 		log.Fatal(err)
+		return
 	}
-	stats.SetCommits(commits)
 
-	// Get data from the commits
-	fileURLs, fileSizes, fileChanges, useStates, err := api.ExtractFileData(commits)
+	token, err := apiCredentials(tokenFlags, &options)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
+	if *teamsPath != "" {
+		options.teams, err = utils.LoadTeams(*teamsPath)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
 
-	stats.SetFileUrls(fileURLs)
-	stats.SetFileSizes(fileSizes)
-	stats.SetFileChanges(fileChanges)
-	stats.SetUseStates(useStates)
-	stats.OutputResults()
+	if len(repos) > 0 {
+		if *format == "html" || *cardsDir != "" || *snapshotPath != "" || *interactive {
+			log.Fatal("--format html, --cards, --save-snapshot and --interactive are not supported with --repos")
+			return
+		}
+		err = runLeaderboard(repos, token, options, *format, *outPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if repoUser == "" {
+		repoUser = utils.GetInput("Repository Owner", utils.Title)
+	}
+	if repoName == "" {
+		repoName = utils.GetInput("Repository Name", utils.Title)
+	}
+
+	stats, api, err := collectStats(repoUser, repoName, token, options)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	switch *format {
+	case "json", "markdown", "html":
+		err = writeReport(stats.Report(5), *format, *outPath)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	case "csv":
+		csvDir := *outPath
+		if csvDir == "" {
+			csvDir = repoName + "-csv"
+		}
+		err = stats.WriteCSV(csvDir)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+		utils.OutputFrom([]string{"CSV written to", csvDir}, []utils.Color{utils.Success, utils.Highlight})
+	default:
+		stats.OutputResults()
+	}
+
+	if *cardsDir != "" {
+		err = stats.Report(5).WriteCards(*cardsDir, cardTheme)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+
+	if *snapshotPath != "" {
+		err = stats.Snapshot().WriteSnapshot(*snapshotPath)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+		utils.OutputFrom([]string{"Snapshot saved to", *snapshotPath}, []utils.Color{utils.Success, utils.Highlight})
+	}
+
+	if *interactive {
+		err = stats.Browse()
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
 
 	//fmt.Println(stats.Files())
 
@@ -70,3 +229,1035 @@ func main() {
 		return
 	}
 }
+
+// collectOptions
+// Options for collecting the statistics of a repository
+type collectOptions struct {
+	depth        int
+	showRenames  bool
+	existingOnly bool
+	// Map of team name to contributors, nil for no teams
+	teams map[string][]string
+	// File to keep collected data in, or directory when collecting several repositories
+	cachePath string
+	// Where the repositories are hosted, "github", "gitlab" or "gitea"
+	provider string
+	// The base URLs of the GitHub instance the repositories are on
+	hosts services.GitHubHosts
+	// The base URL of the GitLab instance the repositories are on
+	gitlabURL string
+	// The base URL of the Gitea or Forgejo instance the repositories are on
+	giteaURL string
+	// The GitHub App to authenticate as instead of a token, nil to use a token
+	app *services.GitHubApp
+	// Map of owner/repo to the location of every listed repository given as a full URL, which is
+	// collected from the instance of its host
+	locations map[string]*repoLocation
+}
+
+// repoLocation
+// A repository given as a full URL, along with the instance it is on
+type repoLocation struct {
+	provider    string
+	owner       string
+	name        string
+	githubHosts services.GitHubHosts
+	gitlabURL   string
+	giteaURL    string
+}
+
+// parseRepoLocation
+// Parses a repository given as a full URL, on GitLab or Gitea if provider is "gitlab" or
+// "gitea" or the host is named like one of their instances, otherwise on GitHub
+func parseRepoLocation(repoURL string, provider string) (*repoLocation, error) {
+	if provider == "gitea" || provider == "forgejo" || (provider == "" && services.IsGiteaURL(repoURL)) {
+		baseURL, owner, name, err := services.ParseGiteaURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		return &repoLocation{provider: "gitea", owner: owner, name: name, giteaURL: baseURL}, nil
+	}
+	if provider == "gitlab" || (provider == "" && services.IsGitLabURL(repoURL)) {
+		baseURL, owner, name, err := services.ParseGitLabURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		return &repoLocation{provider: "gitlab", owner: owner, name: name, gitlabURL: baseURL}, nil
+	}
+	hosts, owner, name, err := services.ParseRepoURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	return &repoLocation{provider: "github", owner: owner, name: name, githubHosts: hosts}, nil
+}
+
+// hostOptions
+// The flags selecting the provider and instance repositories are on
+type hostOptions struct {
+	provider  *string
+	gitlabURL *string
+	giteaURL  *string
+	host      *string
+	api       *string
+	graphQL   *string
+	raw       *string
+}
+
+// addHostFlags
+// Adds the flags selecting the provider and instance to a flag set
+func addHostFlags(flags *flag.FlagSet) hostOptions {
+	return hostOptions{
+		provider:  flags.String("provider", "", "where repositories are hosted: github, gitlab, or gitea (also forgejo), defaults to the host of --repo, or github"),
+		gitlabURL: flags.String("gitlab-url", "", "base URL of a self-hosted GitLab instance, defaults to the host of --repo, or https://gitlab.com"),
+		giteaURL:  flags.String("gitea-url", "", "base URL of a Gitea or Forgejo instance, defaults to the host of --repo"),
+		host:      flags.String("github-host", "", "hostname of a GitHub Enterprise Server, ex: github.example.com, defaults to github.com"),
+		api:       flags.String("api-url", "", "REST API base URL, overrides the one of --github-host, ex: https://github.example.com/api/v3"),
+		graphQL:   flags.String("graphql-url", "", "GraphQL API URL, overrides the one of --github-host, ex: https://github.example.com/api/graphql"),
+		raw:       flags.String("raw-url", "", "raw file contents base URL, overrides the one of --github-host, ex: https://raw.github.example.com"),
+	}
+}
+
+// apply
+// Sets the provider and instance selected by the flags, using those of location when a flag
+// is not set
+//
+// Parameters:
+//   - options: the options to set
+//   - location: the repository given as a full URL, nil if none was given
+//
+// Returns any errors
+func (h hostOptions) apply(options *collectOptions, location *repoLocation) error {
+	options.provider = *h.provider
+	if options.provider == "forgejo" {
+		// Forgejo is a fork of Gitea serving the same API
+		options.provider = "gitea"
+	}
+	if options.provider == "" {
+		options.provider = "github"
+		if location != nil {
+			options.provider = location.provider
+		}
+	}
+
+	switch options.provider {
+	case "github":
+		hosts := services.DefaultGitHubHosts()
+		if *h.host != "" {
+			hosts = services.EnterpriseHosts(*h.host)
+		} else if location != nil {
+			hosts = location.githubHosts
+		}
+		options.hosts = hosts.WithOverrides(*h.api, *h.graphQL, *h.raw)
+	case "gitlab":
+		options.gitlabURL = *h.gitlabURL
+		if options.gitlabURL == "" && location != nil {
+			options.gitlabURL = location.gitlabURL
+		}
+	case "gitea":
+		options.giteaURL = *h.giteaURL
+		if options.giteaURL == "" && location != nil {
+			options.giteaURL = location.giteaURL
+		}
+		if options.giteaURL == "" {
+			return errors.New("--provider gitea needs --gitea-url or --repo given as a full URL")
+		}
+	default:
+		return errors.New("unknown --provider " + options.provider + ", expected github, gitlab or gitea")
+	}
+	return nil
+}
+
+// newProvider
+// Creates the API a repository is collected with
+func newProvider(repoUser string, repoName string, token string, options collectOptions) services.Provider {
+	if location, ok := options.locations[repoUser+"/"+repoName]; ok {
+		switch location.provider {
+		case "github":
+			options.hosts = location.githubHosts
+		case "gitlab":
+			options.gitlabURL = location.gitlabURL
+		case "gitea":
+			options.giteaURL = location.giteaURL
+		}
+	}
+	switch options.provider {
+	case "gitlab":
+		return services.NewGLAPI(repoUser, repoName, token, options.gitlabURL)
+	case "gitea":
+		return services.NewGiteaAPI(repoUser, repoName, token, options.giteaURL)
+	}
+	if options.app != nil {
+		return services.NewGHAPIWithApp(repoUser, repoName, options.app, options.hosts)
+	}
+	return services.NewGHAPIWithHosts(repoUser, repoName, token, options.hosts)
+}
+
+// collectStats
+// Collects the statistics of a repository
+//
+// Parameters:
+//   - repoUser: the owner of the repository
+//   - repoName: the name of the repository
+//   - token: the GitHub, GitLab or Gitea token to authenticate with
+//   - options: options for the collection
+//
+// Returns the statistics and the API used to collect them
+func collectStats(repoUser string, repoName string, token string, options collectOptions) (*utils.Stats, services.Provider, error) {
+	var err error
+
+	// Make stuff
+	api := newProvider(repoUser, repoName, token, options)
+
+	// Get PRs, commits and files, only fetching new activity if a cache exists
+	dataset := services.NewDataset(repoUser, repoName)
+	if options.cachePath != "" {
+		dataset, err = services.LoadDataset(options.cachePath, repoUser, repoName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	err = api.Refresh(dataset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if options.cachePath != "" {
+		err = dataset.Save(options.cachePath)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return statsFromDataset(dataset, api, options), api, nil
+}
+
+// statsFromDataset
+// Builds the statistics of a collected dataset
+//
+// Parameters:
+//   - dataset: the collected dataset
+//   - api: the API the dataset was collected with
+//   - options: options for the statistics
+//
+// Returns the statistics
+func statsFromDataset(dataset *services.Dataset, api services.Provider, options collectOptions) *utils.Stats {
+	stats := utils.NewStats(dataset.RepoOwner, dataset.RepoName,
+		[]string{".png", ".svg", ".jpg", ".lock", ".json", ".log", ".md", ".yml", ".pdf"},
+		[]string{"package-lock.json", "yarn.lock", "package.json"},
+		[]string{".github", ".git", ".husky", "client/docs", "client/node_modules", "client/patches",
+			"server/node_modules"},
+	)
+	stats.SetDirectoryDepth(options.depth)
+	stats.SetShowRenames(options.showRenames)
+	stats.SetExistingOnly(options.existingOnly)
+	stats.SetTeams(options.teams)
+
+	stats.SetPRs(dataset.PRs)
+	stats.SetCommits(dataset.Commits)
+
+	// Get data from the commits
+	fileURLs, fileSizes, fileChanges, useStates, fileHistory := dataset.FileData(api)
+
+	stats.SetFileUrls(fileURLs)
+	stats.SetFileSizes(fileSizes)
+	stats.SetFileChanges(fileChanges)
+	stats.SetFileHistory(fileHistory)
+	stats.SetUseStates(useStates)
+	return stats
+}
+
+// checkFormat
+// Checks that a --format value is one of the supported formats
+func checkFormat(format string, formats ...string) error {
+	for _, supported := range formats {
+		if format == supported {
+			return nil
+		}
+	}
+	return errors.New("unsupported --format " + format + ", expected one of " + strings.Join(formats, ", "))
+}
+
+// repoList
+// Gets the repositories given with --repos and --repos-file, as owner/repo entries or full URLs
+//
+// Parameters:
+//   - reposFlag: the comma separated entries of --repos
+//   - reposFile: the file of --repos-file, empty for none
+//   - provider: the provider selected with --provider, empty to infer it from each URL
+//
+// Returns the owner and name of every repository, and a map of owner/repo to the location of
+// every repository given as a full URL
+func repoList(reposFlag string, reposFile string, provider string) ([][2]string, map[string]*repoLocation, error) {
+	entries := strings.Split(reposFlag, ",")
+	if reposFile != "" {
+		file, err := os.Open(reposFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		fileEntries, err := utils.ReadRepoList(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	// Entries given as full URLs first, as the first one selects the provider when --provider
+	// is not set
+	entryLocations := make([]*repoLocation, len(entries))
+	for index, entry := range entries {
+		if !strings.Contains(entry, "://") {
+			continue
+		}
+		location, err := parseRepoLocation(strings.TrimSpace(entry), provider)
+		if err != nil {
+			return nil, nil, err
+		}
+		entryLocations[index] = location
+		if provider == "" {
+			provider = location.provider
+		}
+	}
+
+	repos := make([][2]string, 0)
+	locations := make(map[string]*repoLocation)
+	for index, entry := range entries {
+		if location := entryLocations[index]; location != nil {
+			repos = append(repos, [2]string{location.owner, location.name})
+			locations[location.owner+"/"+location.name] = location
+			continue
+		}
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		// Only GitLab owners may include subgroups
+		repo, err := utils.ParseRepo(entry, provider == "gitlab")
+		if err != nil {
+			return nil, nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, locations, nil
+}
+
+// firstLocation
+// Gets the location of the first repository of a list given as a full URL, nil if none was
+func firstLocation(repos [][2]string, locations map[string]*repoLocation) *repoLocation {
+	for _, repo := range repos {
+		if location, ok := locations[repo[0]+"/"+repo[1]]; ok {
+			return location
+		}
+	}
+	return nil
+}
+
+// setLocations
+// Sets the locations of listed repositories given as full URLs, which must all be on the
+// provider already selected, as a single token is used for every repository
+func (o *collectOptions) setLocations(locations map[string]*repoLocation) error {
+	for repo, location := range locations {
+		if location.provider != o.provider {
+			return errors.New(repo + " is on " + location.provider + ", but the other repositories are on " +
+				o.provider + ", list repositories of a single provider")
+		}
+	}
+	o.locations = locations
+	return nil
+}
+
+// tokenVariables
+// The environment and .env variables holding the token of each provider, in order of precedence
+var tokenVariables = map[string][]string{
+	"github": {"GITHUB_TOKEN", "GH_TOKEN"},
+	"gitlab": {"GITLAB_TOKEN"},
+	"gitea":  {"GITEA_TOKEN"},
+}
+
+// tokenOptions
+// The flags giving the token to authenticate with
+type tokenOptions struct {
+	token     *string
+	tokenFile *string
+}
+
+// addTokenFlags
+// Adds the flags giving the token to authenticate with to a flag set
+func addTokenFlags(flags *flag.FlagSet) tokenOptions {
+	return tokenOptions{
+		token:     flags.String("token", "", "token to authenticate with, takes precedence over every other source"),
+		tokenFile: flags.String("token-file", "", "file containing the token to authenticate with"),
+	}
+}
+
+// apiCredentials
+// Finds the credentials of the provider, reporting the source used without the token itself.
+// Sources are tried in order:
+//   - --token
+//   - --token-file
+//   - the environment variables of the provider, see tokenVariables
+//   - .env in the working directory, then next to the executable, where a GitHub App takes
+//     precedence over a token, see githubApp
+//   - for GitHub, the GitHub CLI's hosts.yml
+//
+// Parameters:
+//   - flags: the token flags
+//   - options: the options to set the GitHub App of
+//
+// Returns the token, empty when authenticating as a GitHub App or if no source has one
+func apiCredentials(flags tokenOptions, options *collectOptions) (string, error) {
+	token, source, err := findCredentials(flags, options)
+	if err != nil {
+		return "", err
+	}
+	if source == "" {
+		utils.OutputFrom([]string{"No Token Found:", "making unauthenticated requests"},
+			[]utils.Color{utils.Err, utils.Subtle})
+	} else {
+		utils.OutputFrom([]string{"Authenticating With", source}, []utils.Color{utils.Subtle, utils.Highlight})
+	}
+	return token, nil
+}
+
+// findCredentials
+// Finds the credentials of the provider, see apiCredentials
+//
+// Returns the token, a description of its source, empty if none was found, and any errors
+func findCredentials(flags tokenOptions, options *collectOptions) (string, string, error) {
+	if *flags.token != "" {
+		return *flags.token, "--token", nil
+	}
+	if *flags.tokenFile != "" {
+		data, err := os.ReadFile(*flags.tokenFile)
+		if err != nil {
+			return "", "", err
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", "", errors.New("no token in --token-file " + *flags.tokenFile)
+		}
+		return token, "--token-file " + *flags.tokenFile, nil
+	}
+
+	variables := tokenVariables[options.provider]
+	for _, variable := range variables {
+		if token := os.Getenv(variable); token != "" {
+			return token, variable + " environment variable", nil
+		}
+	}
+
+	for _, path := range envFilePaths() {
+		envData, err := readEnvPath(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if options.provider == "github" && envData["GITHUB_APP_ID"] != "" {
+			options.app, err = githubApp(envData, options.hosts)
+			if err != nil {
+				return "", "", err
+			}
+			return "", "GitHub App " + options.app.AppID + " from " + path, nil
+		}
+		for _, variable := range variables {
+			if envData[variable] != "" {
+				return envData[variable], variable + " in " + path, nil
+			}
+		}
+	}
+
+	if options.provider == "github" {
+		path := utils.GHHostsPath()
+		hostsFile, err := os.Open(path)
+		if err == nil {
+			defer hostsFile.Close()
+			token, err := utils.ReadGHToken(hostsFile, githubHostname(options.hosts))
+			if err != nil {
+				return "", "", err
+			}
+			if token != "" {
+				return token, "GitHub CLI " + path, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// githubApp
+// Creates the GitHub App set in .env data by GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY, the app's
+// private key as a PEM or the path of its .pem file, along with GITHUB_APP_INSTALLATION_ID if the
+// installation should not be looked up from each repository
+func githubApp(envData map[string]string, hosts services.GitHubHosts) (*services.GitHubApp, error) {
+	if envData["GITHUB_APP_PRIVATE_KEY"] == "" {
+		return nil, errors.New("GITHUB_APP_ID is set without GITHUB_APP_PRIVATE_KEY")
+	}
+	return services.LoadGitHubApp(envData["GITHUB_APP_ID"], envData["GITHUB_APP_PRIVATE_KEY"],
+		envData["GITHUB_APP_INSTALLATION_ID"], hosts.API)
+}
+
+// githubHostname
+// Gets the hostname the GitHub CLI knows a GitHub instance by, ex: github.com
+func githubHostname(hosts services.GitHubHosts) string {
+	parsed, err := url.Parse(hosts.API)
+	if err != nil || parsed.Hostname() == "api.github.com" {
+		return "github.com"
+	}
+	return parsed.Hostname()
+}
+
+// envFilePaths
+// Gets the .env files settings are read from, the one in the working directory first, then the
+// one next to the executable
+func envFilePaths() []string {
+	paths := []string{".env"}
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err != nil {
+		return paths
+	}
+	path := filepath.Join(filepath.Dir(executable), ".env")
+	if working, err := filepath.Abs(".env"); err == nil && working != path {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// readEnvFile
+// Reads every .env file, see envFilePaths, a variable in an earlier file taking precedence over
+// the same variable in a later one. Missing files are skipped
+func readEnvFile() (map[string]string, error) {
+	envData := make(map[string]string)
+	paths := envFilePaths()
+	for index := len(paths) - 1; index >= 0; index-- {
+		fileData, err := readEnvPath(paths[index])
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileData {
+			envData[key] = value
+		}
+	}
+	return envData, nil
+}
+
+// readEnvPath
+// Reads a single .env file
+func readEnvPath(path string) (map[string]string, error) {
+	envFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer envFile.Close()
+	envData, err := utils.ReadEnv(envFile)
+	if err != nil {
+		return nil, utils.WrapError(err, "readEnvPath", "in "+path)
+	}
+	return envData, nil
+}
+
+// repoCachePath
+// Gets the cache file of a repository inside a cache directory, empty if cacheDir is empty
+func repoCachePath(cacheDir string, repoUser string, repoName string) string {
+	if cacheDir == "" {
+		return ""
+	}
+	// GitLab namespaces may include subgroups, ex: group/subgroup
+	return filepath.Join(cacheDir, strings.ReplaceAll(repoUser, "/", "-")+"-"+repoName+".json")
+}
+
+// runLeaderboard
+// Collects the statistics of several repositories and outputs them ranked against each other
+//
+// Parameters:
+//   - repos: the owner and name of every repository
+//   - token: the GitHub token to authenticate with
+//   - options: options for each collection, the cache path is a directory of one file per repository
+//   - format: output format, text, json, markdown or csv
+//   - outPath: file to write to, or directory for csv
+//
+// Returns any errors
+func runLeaderboard(repos [][2]string, token string, options collectOptions, format string, outPath string) error {
+	err := checkFormat(format, "text", "json", "markdown", "csv")
+	if err != nil {
+		return errors.New("with --repos: " + err.Error())
+	}
+	cacheDir := options.cachePath
+	if cacheDir != "" {
+		err := os.MkdirAll(cacheDir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	allStats := make([]*utils.Stats, 0, len(repos))
+	var api services.Provider
+	for _, repo := range repos {
+		options.cachePath = repoCachePath(cacheDir, repo[0], repo[1])
+		stats, repoAPI, err := collectStats(repo[0], repo[1], token, options)
+		if err != nil {
+			return err
+		}
+		allStats = append(allStats, stats)
+		api = repoAPI
+	}
+	leaderboard := utils.NewLeaderboard(allStats, 5)
+
+	switch format {
+	case "json", "markdown":
+		var w io.Writer = os.Stdout
+		if outPath != "" {
+			file, err := os.Create(outPath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+		if format == "markdown" {
+			return leaderboard.WriteMarkdown(w)
+		}
+		return leaderboard.WriteJSON(w)
+	case "csv":
+		csvDir := outPath
+		if csvDir == "" {
+			csvDir = "leaderboard-csv"
+		}
+		err := leaderboard.WriteCSV(csvDir)
+		if err != nil {
+			return err
+		}
+		// Each repository's own rows in a directory of its own
+		for _, stats := range allStats {
+			err = stats.WriteCSV(filepath.Join(csvDir, stats.RepoUser+"-"+stats.RepoName))
+			if err != nil {
+				return err
+			}
+		}
+		utils.OutputFrom([]string{"CSV written to", csvDir}, []utils.Color{utils.Success, utils.Highlight})
+	default:
+		for _, stats := range allStats {
+			stats.OutputResults()
+		}
+		leaderboard.OutputResults()
+	}
+
+	return utils.OutputFrom([]string{"[Rate Limit]", api.GetRateLimitRemainingString()},
+		[]utils.Color{utils.Subtle, utils.Highlight})
+}
+
+// writeReport
+// Writes a report in the given format to outPath, or to stdout if outPath is empty
+func writeReport(report *utils.Report, format string, outPath string) error {
+	var w io.Writer = os.Stdout
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch format {
+	case "markdown":
+		return report.WriteMarkdown(w)
+	case "html":
+		return report.WriteHTML(w)
+	default:
+		return report.WriteJSON(w)
+	}
+}
+
+// setupColors
+// Sets the color mode and theme of the terminal output
+func setupColors(modeName string, noColor bool, themeName string) error {
+	colorMode, detectColors, err := utils.ParseColorMode(modeName)
+	if err != nil {
+		return err
+	}
+	if noColor {
+		colorMode, detectColors = utils.ColorNone, false
+	}
+	if detectColors {
+		colorMode = utils.DetectColorMode()
+	}
+	utils.SetColorMode(colorMode)
+	theme, err := utils.LoadTheme(themeName)
+	if err != nil {
+		return err
+	}
+	return utils.ApplyTheme(theme)
+}
+
+// setupTransport
+// Records every response to recordDir, or serves responses recorded to replayDir, if either is set
+func setupTransport(recordDir string, replayDir string) error {
+	if recordDir != "" && replayDir != "" {
+		return errors.New("--record and --replay cannot be used together")
+	}
+	if recordDir != "" {
+		recorder, err := utils.NewRecordingTransport(recordDir, nil)
+		if err != nil {
+			return err
+		}
+		utils.SetTransport(recorder)
+	}
+	if replayDir != "" {
+		replayer, err := utils.NewReplayTransport(replayDir)
+		if err != nil {
+			return err
+		}
+		utils.SetTransport(replayer)
+	}
+	return nil
+}
+
+// runDiff
+// Runs the diff command, comparing two snapshots saved with --save-snapshot
+//
+// Parameters:
+//   - args: the command line arguments following "diff"
+//
+// Returns any errors
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: repo_stats diff [options] OLD_SNAPSHOT NEW_SNAPSHOT")
+		flags.PrintDefaults()
+	}
+	top := flags.Int("top", 5, "number of items compared in each ranking")
+	noColor := flags.Bool("no-color", false, "disable colors, same as setting NO_COLOR")
+	colorModeName := flags.String("color", "auto", "color mode: auto, truecolor, 256, 16 or none")
+	themeName := flags.String("theme", "default", "color theme name (default, light, solarized, high-contrast) or JSON theme file")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	err := setupColors(*colorModeName, *noColor, *themeName)
+	if err != nil {
+		return err
+	}
+	older, err := utils.LoadSnapshot(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newer, err := utils.LoadSnapshot(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	diff, err := utils.CompareSnapshots(older, newer, *top)
+	if err != nil {
+		return err
+	}
+	diff.OutputResults()
+	return nil
+}
+
+// runSlack
+// Runs the slack command, outputting the statistics of a Slack workspace export the same way as
+// the statistics of a repository
+//
+// Parameters:
+//   - args: the command line arguments following "slack"
+//
+// Returns any errors
+func runSlack(args []string) error {
+	flags := flag.NewFlagSet("slack", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: repo_stats slack [options] EXPORT_ZIP_OR_DIR")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format: text, json, markdown or html")
+	outPath := flags.String("out", "", "file to write json, markdown or html output to, defaults to stdout")
+	top := flags.Int("top", 5, "number of items in each ranking, 0 for all")
+	channelsFlag := flags.String("channels", "", "comma separated channels to include, defaults to every channel")
+	workspace := flags.String("name", "", "workspace name shown in the output, defaults to the export file name")
+	cardsDir := flags.String("cards", "", "directory to write SVG and PNG highlight cards to")
+	cardColors := flags.String("card-colors", "", "card colors as role=#rrggbb pairs, ex: background=#000000,accent=#ff8800")
+	noColor := flags.Bool("no-color", false, "disable colors, same as setting NO_COLOR")
+	colorModeName := flags.String("color", "auto", "color mode: auto, truecolor, 256, 16 or none")
+	themeName := flags.String("theme", "default", "color theme name (default, light, solarized, high-contrast) or JSON theme file")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	err := checkFormat(*format, "text", "json", "markdown", "html")
+	if err != nil {
+		return errors.New("slack: " + err.Error())
+	}
+
+	if *format != "text" && *outPath == "" {
+		// Keep stdout clean for structured output
+		utils.SetOutput(os.Stderr)
+	}
+	err = setupColors(*colorModeName, *noColor, *themeName)
+	if err != nil {
+		return err
+	}
+	cardTheme, err := utils.ParseCardTheme(*cardColors)
+	if err != nil {
+		return err
+	}
+
+	exportPath := flags.Arg(0)
+	export, err := services.ReadSlackExport(exportPath)
+	if err != nil {
+		return err
+	}
+	channels := export.Channels
+	if *channelsFlag != "" {
+		channels = make([]utils.SlackChannel, 0)
+		for _, name := range strings.Split(*channelsFlag, ",") {
+			name = strings.TrimPrefix(strings.TrimSpace(name), "#")
+			found := false
+			for _, channel := range export.Channels {
+				if channel.Name == name {
+					channels = append(channels, channel)
+					found = true
+				}
+			}
+			if !found {
+				return errors.New("channel not found in export: " + name)
+			}
+		}
+	}
+
+	if *workspace == "" {
+		*workspace = strings.TrimSuffix(filepath.Base(exportPath), filepath.Ext(exportPath))
+	}
+	stats := utils.NewSlackStats(*workspace)
+	stats.SetUsers(export.Users)
+	stats.SetChannels(channels)
+	report := stats.Report(*top)
+
+	if *format == "text" {
+		report.OutputResults()
+	} else {
+		err = writeReport(report, *format, *outPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *cardsDir != "" {
+		return report.WriteCards(*cardsDir, cardTheme)
+	}
+	return nil
+}
+
+// runServe
+// Runs the serve command, serving the statistics of repositories as a JSON API and
+// refreshing them in the background
+//
+// Parameters:
+//   - args: the command line arguments following "serve"
+//
+// Returns any errors
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: repo_stats serve [options]")
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", ":8080", "address to listen on")
+	interval := flags.Duration("interval", time.Hour, "time between background refreshes, 0 to only collect at startup")
+	allowOrigin := flags.String("allow-origin", "*", "Access-Control-Allow-Origin header, empty for none")
+	reposFlag := flags.String("repos", "", "comma separated owner/repo entries to serve")
+	reposFile := flags.String("repos-file", "", "file of owner/repo entries, one per line, to serve")
+	cacheDir := flags.String("cache", "", "directory to keep collected data in, refreshes only fetch new activity")
+	depth := flags.Int("depth", 2, "number of directory levels to roll up")
+	existingOnly := flags.Bool("existing-only", false, "only rank changes to files still in the repository")
+	teamsPath := flags.String("teams", "", "JSON file mapping team names to contributors, to aggregate stats per team")
+	webhookSecret := flags.String("webhook-secret", "", "secret of the GitHub webhook, enables POST /webhook, defaults to WEBHOOK_SECRET in .env")
+	webhookDir := flags.String("save-webhooks", "", "directory to save every verified webhook delivery to, for replaying")
+	noColor := flags.Bool("no-color", false, "disable colors, same as setting NO_COLOR")
+	hostFlags := addHostFlags(flags)
+	tokenFlags := addTokenFlags(flags)
+	flags.Parse(args)
+
+	err := setupColors("auto", *noColor, "default")
+	if err != nil {
+		return err
+	}
+	repos, locations, err := repoList(*reposFlag, *reposFile, *hostFlags.provider)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		return errors.New("serve needs at least one repository, pass --repos or --repos-file")
+	}
+	if *cacheDir != "" {
+		err = os.MkdirAll(*cacheDir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	options := collectOptions{depth: *depth, existingOnly: *existingOnly}
+	err = hostFlags.apply(&options, firstLocation(repos, locations))
+	if err != nil {
+		return err
+	}
+	err = options.setLocations(locations)
+	if err != nil {
+		return err
+	}
+	if *teamsPath != "" {
+		options.teams, err = utils.LoadTeams(*teamsPath)
+		if err != nil {
+			return err
+		}
+	}
+	token, err := apiCredentials(tokenFlags, &options)
+	if err != nil {
+		return err
+	}
+	envData, err := readEnvFile()
+	if err != nil {
+		return err
+	}
+	if *webhookSecret == "" && options.provider == "github" {
+		*webhookSecret = envData["WEBHOOK_SECRET"]
+	}
+
+	// The API each repository was collected with by lowercase owner/repo, as webhook payloads
+	// may use a different casing than --repos. The server collects one repository at a time
+	apis := make(map[string]services.Provider)
+	collect := func(repoUser string, repoName string) (*utils.Stats, error) {
+		repoOptions := options
+		repoOptions.cachePath = repoCachePath(*cacheDir, repoUser, repoName)
+		stats, api, err := collectStats(repoUser, repoName, token, repoOptions)
+		if err == nil {
+			apis[strings.ToLower(repoUser+"/"+repoName)] = api
+		}
+		return stats, err
+	}
+	statsServer := server.New(repos, collect, *interval, *allowOrigin)
+
+	if *webhookSecret != "" {
+		if options.provider != "github" {
+			return errors.New("webhooks are only supported for GitHub repositories")
+		}
+		if *cacheDir == "" {
+			return errors.New("webhooks update the cached dataset, pass --cache")
+		}
+		if *webhookDir != "" {
+			err = os.MkdirAll(*webhookDir, 0755)
+			if err != nil {
+				return err
+			}
+		}
+		statsServer.EnableWebhook(*webhookSecret, func(repoUser string, repoName string, event string, payload []byte) (*utils.Stats, error) {
+			api, ok := apis[strings.ToLower(repoUser+"/"+repoName)]
+			if !ok {
+				return nil, errors.New("repository is not collected yet")
+			}
+			cachePath := repoCachePath(*cacheDir, repoUser, repoName)
+			dataset, err := services.LoadDataset(cachePath, repoUser, repoName)
+			if err != nil {
+				return nil, err
+			}
+			applied, err := dataset.ApplyWebhook(event, payload)
+			if err != nil || !applied {
+				return nil, err
+			}
+			err = dataset.Save(cachePath)
+			if err != nil {
+				return nil, err
+			}
+			if len(dataset.PendingCommits) > 0 {
+				// Fetch the line counts of pushed commits after responding, as GitHub gives up on
+				// deliveries after 10 seconds, rather than at the next polled refresh, which may
+				// never come with --interval 0
+				go statsServer.Update(repoUser+"/"+repoName, func() (*utils.Stats, error) {
+					dataset, err := services.LoadDataset(cachePath, repoUser, repoName)
+					if err != nil {
+						return nil, err
+					}
+					err = dataset.RefreshPending(api)
+					if err != nil {
+						return nil, err
+					}
+					err = dataset.Save(cachePath)
+					if err != nil {
+						return nil, err
+					}
+					return statsFromDataset(dataset, api, options), nil
+				})
+			}
+			return statsFromDataset(dataset, api, options), nil
+		}, *webhookDir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go statsServer.Run(ctx)
+	utils.OutputFrom([]string{"Serving on", *addr}, []utils.Color{utils.Success, utils.Highlight})
+	return server.ListenAndServe(ctx, *addr, statsServer.Handler())
+}
+
+// runWebhookReplay
+// Runs the webhook-replay command, sending saved webhook deliveries to a server signed like
+// GitHub would sign them
+//
+// Parameters:
+//   - args: the command line arguments following "webhook-replay"
+//
+// Returns any errors
+func runWebhookReplay(args []string) error {
+	flags := flag.NewFlagSet("webhook-replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: repo_stats webhook-replay [options] PAYLOAD...")
+		flags.PrintDefaults()
+	}
+	url := flags.String("url", "http://localhost:8080/webhook", "webhook endpoint to send the deliveries to")
+	secret := flags.String("secret", "", "secret to sign the deliveries with, defaults to WEBHOOK_SECRET in .env")
+	event := flags.String("event", "", "event of every delivery, defaults to the event in each file name as saved by --save-webhooks")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if *secret == "" {
+		envData, err := readEnvFile()
+		if err != nil {
+			return err
+		}
+		*secret = envData["WEBHOOK_SECRET"]
+	}
+
+	for _, path := range flags.Args() {
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		deliveryEvent := *event
+		if deliveryEvent == "" {
+			// Saved deliveries are named <time>-<event>-<delivery id>.json
+			items := strings.Split(strings.TrimSuffix(filepath.Base(path), ".json"), "-")
+			if len(items) < 2 {
+				return errors.New("no event in file name, pass --event: " + path)
+			}
+			deliveryEvent = items[1]
+		}
+
+		body, _, err := utils.Post(*url, string(payload), map[string]string{
+			"Content-Type":        "application/json",
+			"X-GitHub-Event":      deliveryEvent,
+			"X-Hub-Signature-256": services.SignWebhook(*secret, payload),
+		})
+		if err != nil {
+			return err
+		}
+		utils.OutputFrom([]string{"[Replayed]", deliveryEvent, path, strings.TrimSpace(body)},
+			[]utils.Color{utils.Success, utils.Highlight, utils.Subtle, utils.Subtle})
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"repo_stats/services"
+	"repo_stats/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector
+// Collects the statistics of a repository, such as from GitHub API
+type Collector func(repoOwner string, repoName string) (*utils.Stats, error)
+
+// Webhook
+// Applies a webhook delivery for a repository, such as to a persisted dataset
+//
+// Returns the statistics rebuilt from the delivery, or nil if the delivery was ignored
+type Webhook func(repoOwner string, repoName string, event string, payload []byte) (*utils.Stats, error)
+
+// maxWebhookSize
+// The largest webhook delivery accepted, GitHub caps payloads at 25 MB
+const maxWebhookSize = 25 << 20
+
+// Server
+// Serves the statistics of a fixed set of repositories as a JSON API, refreshing them in the
+// background so requests never wait on GitHub
+type Server struct {
+	// Every repository served, as owner/name
+	repos       []string
+	collect     Collector
+	interval    time.Duration
+	allowOrigin string
+	// Applies webhook deliveries, nil if webhooks are disabled
+	webhook       Webhook
+	webhookSecret string
+	// Directory every verified delivery is saved to for replaying, empty to not save
+	webhookDir string
+
+	// Held while collecting or applying a webhook, so only one updates a repository at a time
+	collectMutex sync.Mutex
+	mutex        sync.RWMutex
+	// A map of lowercase owner/name to the latest results of the repository
+	results map[string]*result
+}
+
+// result
+// The latest collected statistics of a repository
+type result struct {
+	repo  string
+	stats *utils.Stats
+	// The time stats were collected
+	updatedAt time.Time
+	// The error of the latest refresh, empty if it succeeded
+	lastError string
+	// The time of the latest refresh attempt
+	refreshedAt time.Time
+}
+
+// response
+// The JSON envelope of every successful response
+type response struct {
+	Repo      string    `json:"repo"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// The time of the next background refresh, unset if refreshes are disabled
+	NextRefreshAt time.Time `json:"next_refresh_at,omitzero"`
+	// The error of the latest refresh if it failed, in which case data is from an earlier refresh
+	LastError string      `json:"last_error,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// New
+// Creates a Server for a fixed set of repositories, Run must be called to collect them
+//
+// Parameters:
+//   - repos: the owner and name of every repository to serve
+//   - collect: collects the statistics of a single repository
+//   - interval: the time between background refreshes, 0 or less to only collect at startup
+//   - allowOrigin: the Access-Control-Allow-Origin header sent with every response, empty for none
+//
+// Returns pointer to new Server
+func New(repos [][2]string, collect Collector, interval time.Duration, allowOrigin string) *Server {
+	server := &Server{collect: collect, interval: interval, allowOrigin: allowOrigin,
+		results: make(map[string]*result)}
+	for _, repo := range repos {
+		name := repo[0] + "/" + repo[1]
+		server.repos = append(server.repos, name)
+		server.results[strings.ToLower(name)] = &result{repo: name}
+	}
+	return server
+}
+
+// EnableWebhook
+// Accepts GitHub webhook deliveries on POST /webhook, verified with the X-Hub-Signature-256 header
+//
+// Parameters:
+//   - secret: the secret set on the webhook
+//   - apply: applies a verified delivery
+//   - saveDir: directory to save every verified delivery to for replaying, empty to not save
+func (s *Server) EnableWebhook(secret string, apply Webhook, saveDir string) {
+	s.webhookSecret, s.webhook, s.webhookDir = secret, apply, saveDir
+}
+
+// Handler
+// Gets the handler of every endpoint:
+//   - GET /repos: every served repository and when it was updated
+//   - GET /repos/{owner}/{repo}/stats: the full report, ?top=n sets the items per ranking
+//   - GET /repos/{owner}/{repo}/contributors: every contributor
+//   - GET /repos/{owner}/{repo}/files: every file
+//   - POST /webhook: GitHub webhook deliveries, if enabled with EnableWebhook
+//
+// Returns the handler
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos", s.handleRepos)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/stats", s.handleRepo(func(r *http.Request, stats *utils.Stats) (interface{}, error) {
+		top := 5
+		if value := r.URL.Query().Get("top"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.New("top must be an integer")
+			}
+			top = parsed
+		}
+		return stats.Report(top), nil
+	}))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/contributors", s.handleRepo(func(r *http.Request, stats *utils.Stats) (interface{}, error) {
+		return stats.Contributors(), nil
+	}))
+	mux.HandleFunc("GET /repos/{owner}/{repo}/files", s.handleRepo(func(r *http.Request, stats *utils.Stats) (interface{}, error) {
+		return stats.FileSummaries(), nil
+	}))
+	if s.webhook != nil {
+		mux.HandleFunc("POST /webhook", s.handleWebhook)
+	}
+	return mux
+}
+
+// Run
+// Refreshes every repository immediately and then on every interval, until ctx is done
+//
+// Parameters:
+//   - ctx: stops refreshing when done
+func (s *Server) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		s.RefreshAll(ctx)
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.RefreshAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshAll
+// Collects every repository one at a time, keeping the previous results of any that fail
+//
+// Parameters:
+//   - ctx: stops before the next repository when done
+func (s *Server) RefreshAll(ctx context.Context) {
+	for _, repo := range s.repos {
+		if ctx.Err() != nil {
+			return
+		}
+		s.Refresh(repo)
+	}
+}
+
+// Refresh
+// Collects a single repository, keeping the previous results if it fails
+//
+// Parameters:
+//   - repo: the repository as owner/name
+func (s *Server) Refresh(repo string) {
+	items := strings.SplitN(repo, "/", 2)
+	s.Update(repo, func() (*utils.Stats, error) {
+		return s.collect(items[0], items[1])
+	})
+}
+
+// Update
+// Updates a single repository with statistics from any source, such as the work left after a
+// webhook delivery, keeping the previous results if it fails
+//
+// Parameters:
+//   - repo: the repository as owner/name
+//   - update: gets the new statistics, called while no other collection or webhook runs
+func (s *Server) Update(repo string, update func() (*utils.Stats, error)) {
+	s.collectMutex.Lock()
+	stats, err := update()
+	s.collectMutex.Unlock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current := s.results[strings.ToLower(repo)]
+	current.refreshedAt = time.Now()
+	if err != nil {
+		current.lastError = err.Error()
+		utils.OutputFrom([]string{"[Refresh Failed]", repo, err.Error()},
+			[]utils.Color{utils.Err, utils.Highlight, utils.Subtle})
+		return
+	}
+	current.stats, current.updatedAt, current.lastError = stats, current.refreshedAt, ""
+	utils.OutputFrom([]string{"[Refreshed]", repo}, []utils.Color{utils.Success, utils.Highlight})
+}
+
+// ListenAndServe
+// Serves the API on an address until ctx is done, then shuts down gracefully
+//
+// Parameters:
+//   - ctx: shuts down the server when done
+//   - addr: the address to listen on, ex: ":8080"
+//   - handler: the handler to serve, usually Handler
+//
+// Returns any errors other than the server closing
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	httpServer := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	err := httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// handleRepos
+// Writes every served repository with the time it was updated
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	type repoStatus struct {
+		Repo      string     `json:"repo"`
+		UpdatedAt *time.Time `json:"updated_at"`
+		LastError string     `json:"last_error,omitempty"`
+	}
+
+	s.mutex.RLock()
+	repos := make([]repoStatus, 0, len(s.results))
+	for _, current := range s.results {
+		status := repoStatus{Repo: current.repo, LastError: current.lastError}
+		if current.stats != nil {
+			updatedAt := current.updatedAt
+			status.UpdatedAt = &updatedAt
+		}
+		repos = append(repos, status)
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repo < repos[j].Repo })
+	s.writeJSON(w, http.StatusOK, repos)
+}
+
+// handleRepo
+// Creates a handler which writes data built from the latest statistics of a repository
+//
+// Parameters:
+//   - build: builds the data of the response from the statistics
+//
+// Returns the handler
+func (s *Server) handleRepo(build func(r *http.Request, stats *utils.Stats) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("owner") + "/" + r.PathValue("repo")
+
+		s.mutex.RLock()
+		current, ok := s.results[strings.ToLower(repo)]
+		var snapshot result
+		if ok {
+			snapshot = *current
+		}
+		s.mutex.RUnlock()
+
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "repository is not served: "+repo)
+			return
+		}
+		if snapshot.stats == nil {
+			w.Header().Set("Retry-After", "60")
+			s.writeError(w, http.StatusServiceUnavailable, "statistics are not collected yet")
+			return
+		}
+
+		data, err := build(r, snapshot.stats)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		body := response{Repo: snapshot.repo, UpdatedAt: snapshot.updatedAt, LastError: snapshot.lastError,
+			Data: data}
+		if s.interval > 0 {
+			body.NextRefreshAt = snapshot.refreshedAt.Add(s.interval)
+		}
+		w.Header().Set("Last-Modified", snapshot.updatedAt.UTC().Format(http.TimeFormat))
+		s.writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// handleWebhook
+// Verifies a GitHub webhook delivery, applies it and serves the rebuilt statistics
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		s.writeError(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	}
+	if !services.VerifyWebhookSignature(s.webhookSecret, payload, r.Header.Get("X-Hub-Signature-256")) {
+		s.writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if s.webhookDir != "" {
+		err = saveDelivery(s.webhookDir, event, r.Header.Get("X-GitHub-Delivery"), payload)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if event == "ping" {
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	}
+
+	repoOwner, repoName, err := services.WebhookRepo(payload)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	repo := repoOwner + "/" + repoName
+	s.mutex.RLock()
+	_, ok := s.results[strings.ToLower(repo)]
+	s.mutex.RUnlock()
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "repository is not served: "+repo)
+		return
+	}
+
+	s.collectMutex.Lock()
+	stats, err := s.webhook(repoOwner, repoName, event, payload)
+	s.collectMutex.Unlock()
+	if err != nil {
+		utils.OutputFrom([]string{"[Webhook Failed]", repo, event, err.Error()},
+			[]utils.Color{utils.Err, utils.Highlight, utils.Subtle, utils.Subtle})
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if stats == nil {
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	s.mutex.Lock()
+	current := s.results[strings.ToLower(repo)]
+	current.stats, current.updatedAt = stats, time.Now()
+	s.mutex.Unlock()
+	utils.OutputFrom([]string{"[Webhook]", repo, event}, []utils.Color{utils.Success, utils.Highlight, utils.Subtle})
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "applied"})
+}
+
+// saveDelivery
+// Saves a webhook delivery as <time>-<event>-<delivery id>.json, for replaying later
+func saveDelivery(dir string, event string, delivery string, payload []byte) error {
+	name := fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405.000000000"), event)
+	if delivery != "" {
+		name += "-" + delivery
+	}
+	err := os.WriteFile(filepath.Join(dir, filepath.Base(name)+".json"), payload, 0644)
+	if err != nil {
+		return utils.WrapError(err, "saveDelivery", "while saving "+name)
+	}
+	return nil
+}
+
+// writeJSON
+// Writes a value as JSON with a status code
+func (s *Server) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.allowOrigin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.allowOrigin)
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError
+// Writes an error message as JSON with a status code
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"repo_stats/utils"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DatasetVersion
+// The version of the dataset file format written by Dataset.Save
+const DatasetVersion = 1
+
+// DatasetFile
+// A file in the tree of the main branch, as of the last refresh
+type DatasetFile struct {
+	// The blob SHA, used to only download files which changed
+	SHA       string `json:"sha"`
+	Lines     int    `json:"lines"`
+	UseStates int    `json:"use_states"`
+}
+
+// Dataset
+// Everything collected from GitHub for a repository, kept between runs so a refresh only
+// fetches new activity
+type Dataset struct {
+	Version   int    `json:"version"`
+	RepoOwner string `json:"repo_owner"`
+	RepoName  string `json:"repo_name"`
+	// The time the last refresh started, PRs updated since are fetched by the next refresh
+	RefreshedAt time.Time `json:"refreshed_at"`
+	// Every PR, as returned from GitHub API
+	PRs []interface{} `json:"prs"`
+	// Every commit, newest first, as returned from GitHub API
+	Commits []interface{} `json:"commits"`
+	// The change to every file in every commit, newest commit first
+	FileHistory []utils.FileChange `json:"file_history"`
+	// A map of path to every file in the tree of the main branch
+	Files map[string]DatasetFile `json:"files"`
+	// SHAs of commits received by webhook, whose line counts the next refresh fetches
+	PendingCommits []string `json:"pending_commits,omitempty"`
+	// True if a force push was received by webhook, so the next refresh fetches every commit
+	NeedsFullRefresh bool `json:"needs_full_refresh,omitempty"`
+}
+
+// NewDataset
+// Creates an empty Dataset, the first refresh fetches everything
+func NewDataset(repoOwner string, repoName string) *Dataset {
+	return &Dataset{Version: DatasetVersion, RepoOwner: repoOwner, RepoName: repoName,
+		PRs: make([]interface{}, 0), Commits: make([]interface{}, 0),
+		FileHistory: make([]utils.FileChange, 0), Files: make(map[string]DatasetFile)}
+}
+
+// LoadDataset
+// Reads a dataset saved with Dataset.Save
+//
+// Parameters:
+//   - path: the file to read
+//   - repoOwner: the owner of the repository the dataset must be for
+//   - repoName: the name of the repository the dataset must be for
+//
+// Returns pointer to the dataset, or an empty dataset if the file does not exist
+func LoadDataset(path string, repoOwner string, repoName string) (*Dataset, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewDataset(repoOwner, repoName), nil
+	}
+	if err != nil {
+		return nil, utils.WrapError(err, "LoadDataset", "while reading "+path)
+	}
+
+	dataset := NewDataset(repoOwner, repoName)
+	err = json.Unmarshal(data, dataset)
+	if err != nil {
+		return nil, utils.WrapError(err, "LoadDataset", "while parsing "+path)
+	}
+	if dataset.Version != DatasetVersion {
+		return nil, utils.WrapError(errors.New("unsupported dataset version "+strconv.Itoa(dataset.Version)),
+			"LoadDataset", path)
+	}
+	if dataset.RepoOwner != repoOwner || dataset.RepoName != repoName {
+		return nil, utils.WrapError(errors.New("dataset is for "+dataset.RepoOwner+"/"+dataset.RepoName),
+			"LoadDataset", path)
+	}
+	return dataset, nil
+}
+
+// Save
+// Writes the dataset as JSON to a file
+//
+// Parameters:
+//   - path: the file to write to
+//
+// Returns any errors
+func (d *Dataset) Save(path string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return utils.WrapError(err, "Save", "while encoding dataset")
+	}
+	// Write then rename, so an interrupted save never loses the previous dataset
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return utils.WrapError(err, "Save", "while writing "+path)
+	}
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return utils.WrapError(err, "Save", "while writing "+path)
+	}
+	return nil
+}
+
+// FileData
+// Gets the file data of the dataset, with changes accumulated under the current path of renamed
+// files
+//
+// Parameters:
+//   - api: the API the dataset was collected with, used to build file URLs
+//
+// Returns maps of path to URL, lines, changes and useState calls, and the file history
+func (d *Dataset) FileData(api Provider) (map[string]string, map[string]int, map[string]int, map[string]int, []utils.FileChange) {
+	fileURLMap := make(map[string]string)
+	fileSizeMap := make(map[string]int)
+	numUseStateMap := make(map[string]int)
+	fileChangesMap := make(map[string]int)
+
+	// Accumulate changes under the current path of renamed files
+	followRenames(d.FileHistory)
+	for _, change := range d.FileHistory {
+		fileChangesMap[change.Path] += change.Changes
+	}
+	for fileName, file := range d.Files {
+		fileURLMap[fileName] = api.FileURL(fileName)
+		fileSizeMap[fileName] = file.Lines
+		numUseStateMap[fileName] = file.UseStates
+	}
+	return fileURLMap, fileSizeMap, fileChangesMap, numUseStateMap, d.FileHistory
+}
+
+// Provider
+// A source of repository data, such as GitHub or GitLab, which collects into a Dataset
+type Provider interface {
+	// Refresh fetches activity since the last refresh and merges it into the dataset
+	Refresh(dataset *Dataset) error
+	// FileURL gets the URL of the contents of a file on the default branch
+	FileURL(fileName string) string
+	// GetRateLimitRemainingString gets the number of requests left before the rate limit
+	GetRateLimitRemainingString() string
+}
+
+// source
+// The requests a Provider makes to refresh a Dataset, with every result in the format of
+// GitHub API results so Stats reads them the same way
+type source interface {
+	// getCommits gets the commits of the default branch, newest first, stopping before the
+	// first commit stop returns true for
+	getCommits(stop func(commit interface{}) bool) ([]interface{}, error)
+	// getCommitChanges gets the change to every file in a commit
+	getCommitChanges(commit interface{}) ([]utils.FileChange, error)
+	// commitRef gets a commit, as passed to getCommitChanges, from its SHA alone
+	commitRef(sha string) interface{}
+	// getUpdatedPRs gets every PR, most recently updated first, stopping before the first PR
+	// stop returns true for
+	getUpdatedPRs(stop func(pr interface{}) bool) ([]interface{}, error)
+	// getMainTree gets every file in the tree of the default branch
+	getMainTree() ([]treeFile, error)
+	// getFileStats downloads a file and counts its lines and useState calls
+	getFileStats(fileName string) (int, int, error)
+}
+
+// Refresh
+// Fetches activity since the last refresh and merges it into the dataset: commits not yet
+// in the dataset, PRs updated since the last refresh, and files whose blob SHA changed, along with the details of commits received by webhook. An empty dataset
+// fetches everything
+//
+// Parameters:
+//   - dataset: the dataset to refresh
+//
+// Returns any errors, the dataset is unchanged on error
+func (x *GHAPI) Refresh(dataset *Dataset) error {
+	return refreshDataset(x, dataset)
+}
+
+// refreshDataset
+// Refreshes a dataset from any source, see GHAPI.Refresh
+func refreshDataset(x source, dataset *Dataset) error {
+	refreshedAt := time.Now()
+
+	// Commits are newest first, but commits of a merged branch dated before the newest known
+	// commit are listed after it, so keep listing until a whole page of commits is known
+	knownSHAs := make(map[string]bool)
+	if !dataset.NeedsFullRefresh {
+		for _, commit := range dataset.Commits {
+			knownSHAs[commitSHA(commit)] = true
+		}
+	}
+	knownRun := 0
+	listed, err := x.getCommits(func(commit interface{}) bool {
+		if !knownSHAs[commitSHA(commit)] {
+			knownRun = 0
+			return false
+		}
+		knownRun++
+		return knownRun >= knownCommitRun
+	})
+	if err != nil {
+		return err
+	}
+	foundKnown := len(knownSHAs) > 0 && containsCommit(listed, commitSHA(dataset.Commits[0]))
+	if len(knownSHAs) > 0 && !foundKnown {
+		// The newest known commit is no longer in the history, such as after a force push, so
+		// every commit is fetched again
+		knownSHAs = make(map[string]bool)
+		listed, err = x.getCommits(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	commits := make([]interface{}, 0)
+	fileHistory := make([]utils.FileChange, 0)
+	for _, commit := range listed {
+		if knownSHAs[commitSHA(commit)] {
+			continue
+		}
+		changes, err := x.getCommitChanges(commit)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, commit)
+		fileHistory = append(fileHistory, changes...)
+	}
+
+	// Commits received by webhook only list changed paths, so get their line counts
+	pendingChanges := make(map[string][]utils.FileChange)
+	if foundKnown {
+		pendingChanges, err = fetchPendingChanges(x, dataset.PendingCommits)
+		if err != nil {
+			return err
+		}
+	}
+
+	// PRs sorted by most recently updated, so stop at the first PR not updated since
+	prs, err := x.getUpdatedPRs(func(pr interface{}) bool {
+		updated, err := time.Parse(time.RFC3339, pr.(map[string]interface{})["updated_at"].(string))
+		return err == nil && !dataset.RefreshedAt.IsZero() && updated.Before(dataset.RefreshedAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	tree, err := x.getMainTree()
+	if err != nil {
+		return err
+	}
+	files := make(map[string]DatasetFile)
+	for _, item := range tree {
+		if known, ok := dataset.Files[item.Path]; ok && known.SHA == item.SHA {
+			files[item.Path] = known
+			continue
+		}
+		lines, useStates, err := x.getFileStats(item.Path)
+		if err != nil {
+			return err
+		}
+		files[item.Path] = DatasetFile{SHA: item.SHA, Lines: lines, UseStates: useStates}
+	}
+
+	if foundKnown {
+		for sha, changes := range pendingChanges {
+			dataset.FileHistory = replaceChanges(dataset.FileHistory, sha, changes)
+		}
+		dataset.Commits, dataset.FileHistory = mergeCommits(listed, dataset.Commits,
+			append(fileHistory, dataset.FileHistory...))
+	} else {
+		// Either the dataset was empty or every commit was fetched again
+		dataset.Commits, dataset.FileHistory = commits, fileHistory
+	}
+	dataset.PendingCommits, dataset.NeedsFullRefresh = nil, false
+	dataset.PRs = mergePRs(dataset.PRs, prs)
+	dataset.Files = files
+	dataset.RefreshedAt = refreshedAt
+	return nil
+}
+
+// RefreshPending
+// Fetches the line counts of commits received by webhook, without the rest of a Refresh. Files
+// changed by the commits are downloaded by the next Refresh
+//
+// Parameters:
+//   - api: the API the dataset was collected with
+//
+// Returns any errors, the dataset is unchanged on error
+func (d *Dataset) RefreshPending(api Provider) error {
+	x, ok := api.(source)
+	if !ok {
+		return utils.WrapError(errors.New("provider cannot fetch commits"), "RefreshPending", "")
+	}
+	pendingChanges, err := fetchPendingChanges(x, d.PendingCommits)
+	if err != nil {
+		return err
+	}
+	for sha, changes := range pendingChanges {
+		d.FileHistory = replaceChanges(d.FileHistory, sha, changes)
+	}
+	d.PendingCommits = nil
+	return nil
+}
+
+// fetchPendingChanges
+// Gets the changes of every commit received by webhook
+//
+// Returns a map of SHA to the changes of the commit
+func fetchPendingChanges(x source, shas []string) (map[string][]utils.FileChange, error) {
+	pendingChanges := make(map[string][]utils.FileChange)
+	for _, sha := range shas {
+		changes, err := x.getCommitChanges(x.commitRef(sha))
+		if err != nil {
+			return nil, err
+		}
+		pendingChanges[sha] = changes
+	}
+	return pendingChanges, nil
+}
+
+// knownCommitRun
+// The number of known commits in a row after which the rest of the history is known, a page
+// of GitHub API commits
+const knownCommitRun = 100
+
+// commitSHA
+// Gets the SHA of a commit in the format of GitHub API commits
+func commitSHA(commit interface{}) string {
+	sha, _ := commit.(map[string]interface{})["sha"].(string)
+	return sha
+}
+
+// containsCommit
+// Checks if a list of commits has the commit with a SHA
+func containsCommit(commits []interface{}, sha string) bool {
+	for _, commit := range commits {
+		if commitSHA(commit) == sha {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeCommits
+// Merges listed commits, newest first, with the known commits, which keep their order after
+// every listed commit
+//
+// Parameters:
+//   - listed: the commits listed by a refresh, both new and known
+//   - known: the commits of the dataset
+//   - history: the changes of every new and known commit
+//
+// Returns the merged commits, and the file history in the same order
+func mergeCommits(listed []interface{}, known []interface{}, history []utils.FileChange) ([]interface{}, []utils.FileChange) {
+	changesBySHA := make(map[string][]utils.FileChange)
+	for _, change := range history {
+		changesBySHA[change.SHA] = append(changesBySHA[change.SHA], change)
+	}
+
+	commits := make([]interface{}, 0, len(listed)+len(known))
+	fileHistory := make([]utils.FileChange, 0, len(history))
+	seen := make(map[string]bool)
+	for _, list := range [][]interface{}{listed, known} {
+		for _, commit := range list {
+			sha := commitSHA(commit)
+			if seen[sha] {
+				continue
+			}
+			seen[sha] = true
+			commits = append(commits, commit)
+			fileHistory = append(fileHistory, changesBySHA[sha]...)
+		}
+	}
+	return commits, fileHistory
+}
+
+// mergePRs
+// Merges updated PRs into known PRs by number, newest PR first
+func mergePRs(known []interface{}, updated []interface{}) []interface{} {
+	byNumber := make(map[int]interface{})
+	for _, prs := range [][]interface{}{known, updated} {
+		for _, pr := range prs {
+			number, _ := pr.(map[string]interface{})["number"].(float64)
+			byNumber[int(number)] = pr
+		}
+	}
+	numbers := make([]int, 0, len(byNumber))
+	for number := range byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+
+	merged := make([]interface{}, 0, len(numbers))
+	for _, number := range numbers {
+		merged = append(merged, byNumber[number])
+	}
+	return merged
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"reflect"
+	"repo_stats/utils"
+	"testing"
+)
+
+// fakeSource
+// A source serving a fixed history, recording the commits whose changes are fetched
+type fakeSource struct {
+	commits []interface{}
+	prs     []interface{}
+	tree    []treeFile
+	fetched []string
+}
+
+func (f *fakeSource) getCommits(stop func(commit interface{}) bool) ([]interface{}, error) {
+	return takeUntil(f.commits, stop), nil
+}
+
+func (f *fakeSource) getCommitChanges(commit interface{}) ([]utils.FileChange, error) {
+	sha := commitSHA(commit)
+	f.fetched = append(f.fetched, sha)
+	return []utils.FileChange{{SHA: sha, Author: "Ada", Path: sha + ".go", CommitPath: sha + ".go",
+		Status: "added", Additions: 1, Changes: 1}}, nil
+}
+
+func (f *fakeSource) commitRef(sha string) interface{} {
+	return map[string]interface{}{"sha": sha}
+}
+
+func (f *fakeSource) getUpdatedPRs(stop func(pr interface{}) bool) ([]interface{}, error) {
+	return takeUntil(f.prs, stop), nil
+}
+
+func (f *fakeSource) getMainTree() ([]treeFile, error) {
+	return f.tree, nil
+}
+
+func (f *fakeSource) getFileStats(fileName string) (int, int, error) {
+	return 10, 0, nil
+}
+
+// takeUntil
+// Gets the items before the first one stop returns true for
+func takeUntil(items []interface{}, stop func(item interface{}) bool) []interface{} {
+	result := make([]interface{}, 0)
+	for _, item := range items {
+		if stop != nil && stop(item) {
+			break
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// commitList
+// Creates commits in the format of GitHub API commits from their SHAs, newest first
+func commitList(shas ...string) []interface{} {
+	commits := make([]interface{}, 0, len(shas))
+	for _, sha := range shas {
+		commits = append(commits, map[string]interface{}{"sha": sha})
+	}
+	return commits
+}
+
+// historySHAs
+// Gets the SHA of every change in a file history
+func historySHAs(history []utils.FileChange) []string {
+	shas := make([]string, 0, len(history))
+	for _, change := range history {
+		shas = append(shas, change.SHA)
+	}
+	return shas
+}
+
+func TestRefreshDatasetMergedBranch(t *testing.T) {
+	source := &fakeSource{commits: commitList("c3", "c1")}
+	dataset := NewDataset("owner", "repo")
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+
+	// A branch started before c3 is merged, its commit b1 is dated before c3 so it is
+	// listed after it
+	source.commits = commitList("m1", "c3", "b1", "c1")
+	source.fetched = nil
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+	if want := []string{"m1", "b1"}; !reflect.DeepEqual(source.fetched, want) {
+		t.Errorf("fetched changes of %v, want %v", source.fetched, want)
+	}
+
+	// The refreshed dataset matches one collected from scratch
+	fresh := NewDataset("owner", "repo")
+	if err := refreshDataset(&fakeSource{commits: source.commits}, fresh); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+	if !reflect.DeepEqual(dataset.Commits, fresh.Commits) {
+		t.Errorf("Commits = %v, want %v", dataset.Commits, fresh.Commits)
+	}
+	if got, want := historySHAs(dataset.FileHistory), historySHAs(fresh.FileHistory); !reflect.DeepEqual(got, want) {
+		t.Errorf("FileHistory SHAs = %v, want %v", got, want)
+	}
+}
+
+func TestRefreshDatasetStopsAfterKnownPage(t *testing.T) {
+	shas := make([]string, 0)
+	for i := 0; i < knownCommitRun*2; i++ {
+		shas = append(shas, "k"+string(rune('a'+i/26))+string(rune('a'+i%26)))
+	}
+	source := &fakeSource{commits: commitList(shas...)}
+	dataset := NewDataset("owner", "repo")
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+
+	listed := 0
+	source.commits = commitList(append([]string{"new"}, shas...)...)
+	source.fetched = nil
+	err := refreshDataset(countingSource{source, &listed}, dataset)
+	if err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+	if !reflect.DeepEqual(source.fetched, []string{"new"}) {
+		t.Errorf("fetched changes of %v, want [new]", source.fetched)
+	}
+	if want := 1 + knownCommitRun; listed != want {
+		t.Errorf("listed %d commits, want %d", listed, want)
+	}
+	if len(dataset.Commits) != len(shas)+1 || commitSHA(dataset.Commits[0]) != "new" {
+		t.Errorf("got %d commits starting with %v, want %d starting with new", len(dataset.Commits),
+			dataset.Commits[0], len(shas)+1)
+	}
+}
+
+// countingSource
+// A fakeSource which counts the commits listed, including the one stopped at
+type countingSource struct {
+	*fakeSource
+	listed *int
+}
+
+func (c countingSource) getCommits(stop func(commit interface{}) bool) ([]interface{}, error) {
+	return c.fakeSource.getCommits(func(commit interface{}) bool {
+		*c.listed++
+		return stop != nil && stop(commit)
+	})
+}
+
+func TestRefreshDatasetForcePush(t *testing.T) {
+	source := &fakeSource{commits: commitList("c3", "c2", "c1")}
+	dataset := NewDataset("owner", "repo")
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+
+	// c3 and c2 were rewritten into c4
+	source.commits = commitList("c4", "c1")
+	source.fetched = nil
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+	if !reflect.DeepEqual(dataset.Commits, commitList("c4", "c1")) {
+		t.Errorf("Commits = %v, want [c4 c1]", dataset.Commits)
+	}
+	if want := []string{"c4", "c1"}; !reflect.DeepEqual(historySHAs(dataset.FileHistory), want) {
+		t.Errorf("FileHistory SHAs = %v, want %v", historySHAs(dataset.FileHistory), want)
+	}
+}
+
+func TestRefreshDatasetPendingCommits(t *testing.T) {
+	source := &fakeSource{commits: commitList("c1")}
+	dataset := NewDataset("owner", "repo")
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+
+	// c2 was received by webhook, without line counts
+	dataset.Commits = append(commitList("c2"), dataset.Commits...)
+	dataset.FileHistory = append([]utils.FileChange{{SHA: "c2", Path: "c2.go", Status: "added"}},
+		dataset.FileHistory...)
+	dataset.PendingCommits = []string{"c2"}
+	source.commits = commitList("c2", "c1")
+	source.fetched = nil
+	if err := refreshDataset(source, dataset); err != nil {
+		t.Fatalf("refreshDataset() error = %v", err)
+	}
+	if !reflect.DeepEqual(source.fetched, []string{"c2"}) {
+		t.Errorf("fetched changes of %v, want [c2]", source.fetched)
+	}
+	if len(dataset.PendingCommits) > 0 || dataset.FileHistory[0].Changes != 1 || len(dataset.FileHistory) != 2 {
+		t.Errorf("FileHistory = %+v, pending %v, want c2 with its line counts", dataset.FileHistory,
+			dataset.PendingCommits)
+	}
+}
+
+func TestMergePRs(t *testing.T) {
+	pr := func(number float64, title string) interface{} {
+		return map[string]interface{}{"number": number, "title": title}
+	}
+	tests := []struct {
+		name    string
+		known   []interface{}
+		updated []interface{}
+		want    []interface{}
+	}{
+		{name: "empty", known: []interface{}{}, updated: []interface{}{}, want: []interface{}{}},
+		{name: "new PRs", known: []interface{}{pr(1, "a")}, updated: []interface{}{pr(3, "c"), pr(2, "b")},
+			want: []interface{}{pr(3, "c"), pr(2, "b"), pr(1, "a")}},
+		{name: "updated PR replaces known", known: []interface{}{pr(2, "b"), pr(1, "a")},
+			updated: []interface{}{pr(1, "renamed")}, want: []interface{}{pr(2, "b"), pr(1, "renamed")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mergePRs(test.known, test.updated); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergePRs() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"repo_stats/utils"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -18,19 +17,60 @@ type GHAPI struct {
 	rateLimitRemaining int
 	rateLimitReset     time.Time
 	authToken          string
-	stats              utils.Stats
+	// The GitHub App to authenticate as instead of authToken, nil to use authToken
+	app *GitHubApp
+	// The base URLs of the GitHub instance the repository is on
+	Hosts GitHubHosts
+	stats utils.Stats
 }
 
 func NewGHAPI(repoOwner, repoName string, authToken string) *GHAPI {
+	return NewGHAPIWithHosts(repoOwner, repoName, authToken, DefaultGitHubHosts())
+}
+
+// NewGHAPIWithHosts
+// Creates a new GHAPI for a repository on any GitHub instance, such as a GitHub Enterprise Server
+//
+// Parameters:
+//   - repoOwner: the owner of the repository
+//   - repoName: the name of the repository
+//   - authToken: the token to authenticate with
+//   - hosts: the base URLs of the GitHub instance
+//
+// Returns pointer to new GHAPI
+func NewGHAPIWithHosts(repoOwner, repoName string, authToken string, hosts GitHubHosts) *GHAPI {
+	api := &GHAPI{RepoOwner: repoOwner, RepoName: repoName, RequestCategory: "", Verbose: true,
+		rateLimitRemaining: 5000, authToken: authToken, Hosts: hosts}
+	api.updateRateLimit()
+	return api
+}
+
+// NewGHAPIWithApp
+// Creates a new GHAPI authenticated as an installation of a GitHub App, whose token is
+// refreshed automatically
+//
+// Parameters:
+//   - repoOwner: the owner of the repository
+//   - repoName: the name of the repository
+//   - app: the GitHub App installed on the repository
+//   - hosts: the base URLs of the GitHub instance
+//
+// Returns pointer to new GHAPI
+func NewGHAPIWithApp(repoOwner, repoName string, app *GitHubApp, hosts GitHubHosts) *GHAPI {
 	api := &GHAPI{RepoOwner: repoOwner, RepoName: repoName, RequestCategory: "", Verbose: true,
-		rateLimitRemaining: 5000, authToken: authToken}
-	api.RequestCategory = "Rate Limit"
-	// Make any call to set the rate limit given the response header
-	api.makeRequest("https://api.github.com/rate_limit", "")
-	api.RequestCategory = ""
+		rateLimitRemaining: 5000, app: app, Hosts: hosts}
+	api.updateRateLimit()
 	return api
 }
 
+// updateRateLimit
+// Makes any call to set the rate limit given the response header
+func (x *GHAPI) updateRateLimit() {
+	x.RequestCategory = "Rate Limit"
+	x.makeRequest(x.Hosts.API+"/rate_limit", "")
+	x.RequestCategory = ""
+}
+
 // makeRequest
 //
 // Make a request to the GitHub API. Used to force update of rate-limit
@@ -45,16 +85,26 @@ func (x *GHAPI) makeRequest(url string, body string) (string, http.Header, error
 		// If we have hit the rate limit, wait for the rest
 		timeToWait := x.rateLimitReset.Sub(time.Now())
 		utils.OutputFrom([]string{"Rate Limit Hit:", "Waiting For",
-			strconv.FormatInt(int64(timeToWait), 10)},
+			timeToWait.Round(time.Second).String()},
 			[]utils.Color{utils.Err, utils.Subtle, utils.Subtle})
-		time.Sleep(timeToWait * time.Second)
+		// A reset in the past, such as in a replayed recording, returns right away
+		time.Sleep(timeToWait)
 	}
 
-	respBody, header, err := utils.Get(url, body,
-		map[string]string{
-			"Accept":        "application/vnd.github+json",
-			"Authorization": "Bearer " + x.authToken,
-		})
+	token := x.authToken
+	if x.app != nil {
+		var err error
+		token, err = x.app.Token(x.RepoOwner, x.RepoName)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if token != "" {
+		// Without a token, public repositories can still be read at a lower rate limit
+		headers["Authorization"] = "Bearer " + token
+	}
+	respBody, header, err := utils.Get(url, body, headers)
 	if err != nil {
 		return "", nil, err
 	}
@@ -84,69 +134,36 @@ func (x *GHAPI) GetRateLimitReset() time.Time {
 	return x.rateLimitReset
 }
 
-func (x *GHAPI) GetPRs() ([]interface{}, error) {
-	x.RequestCategory = "Pull Requests"
-	// Make initial call
-	formattedUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls?state=all",
-		x.RepoOwner, x.RepoName)
-	body, headers, err := x.makeRequest(formattedUrl, "")
-	if err != nil {
-		return nil, err
-	}
-	parsedBody, err := utils.ParseBody(body)
-	if err != nil {
-		return nil, err
-	}
-	prs := parsedBody.([]interface{})
-
+// getPages
+// Gets every item of a paginated list, following the Link header
+//
+// Parameters:
+//   - url: the first page of the list
+//   - stop: called for each item in order, returning true stops before that item without
+//     requesting further pages, nil to get every item
+//
+// Returns every item before the stop
+func (x *GHAPI) getPages(url string, stop func(item interface{}) bool) ([]interface{}, error) {
+	items := make([]interface{}, 0)
 	//	While there are more pages, explore them
-	nextLink := parseNextLinkRegex(headers.Get("Link"))
-	for nextLink != "" {
-		body, headers, err = x.makeRequest(nextLink, "")
-		if err != nil {
-			return nil, err
-		}
-		parsedBody, err = utils.ParseBody(body)
+	for url != "" {
+		body, headers, err := x.makeRequest(url, "")
 		if err != nil {
 			return nil, err
 		}
-		prs = append(prs, parsedBody.([]interface{})...)
-		nextLink = parseNextLinkRegex(headers.Get("Link"))
-	}
-
-	return prs, nil
-}
-
-func (x *GHAPI) GetCommits() ([]interface{}, error) {
-	x.RequestCategory = "Commits"
-	// Make initial call
-	formattedUrl := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits",
-		x.RepoOwner, x.RepoName)
-	body, headers, err := x.makeRequest(formattedUrl, "")
-	if err != nil {
-		return nil, err
-	}
-	parsedBody, err := utils.ParseBody(body)
-	if err != nil {
-		return nil, err
-	}
-	commits := parsedBody.([]interface{})
-
-	//	While there are more pages, explore them
-	nextLink := parseNextLinkRegex(headers.Get("Link"))
-	for nextLink != "" {
-		body, headers, err = x.makeRequest(nextLink, "")
+		parsedBody, err := utils.ParseBody(body)
 		if err != nil {
 			return nil, err
 		}
-		parsedBody, err = utils.ParseBody(body)
-		if err != nil {
-			return nil, err
+		for _, item := range parsedBody.([]interface{}) {
+			if stop != nil && stop(item) {
+				return items, nil
+			}
+			items = append(items, item)
 		}
-		commits = append(commits, parsedBody.([]interface{})...)
-		nextLink = parseNextLinkRegex(headers.Get("Link"))
+		url = parseNextLinkRegex(headers.Get("Link"))
 	}
-	return commits, nil
+	return items, nil
 }
 
 func (x *GHAPI) getCommitData(commit interface{}) (interface{}, error) {
@@ -172,9 +189,20 @@ func (x *GHAPI) downloadFileContent(fileURL string) (string, error) {
 	return file, nil
 }
 
-func (x *GHAPI) GetAllFilesFromMainBranch() ([]string, error) {
+// treeFile
+// A file in the tree of the main branch
+type treeFile struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	// The blob SHA, which changes whenever the file contents change
+	SHA string `json:"sha"`
+}
+
+// getMainTree
+// Gets every file, but not directory, in the tree of the latest commit on the main branch
+func (x *GHAPI) getMainTree() ([]treeFile, error) {
 	// First, get the main branch's latest commit SHA
-	branchURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches/main", x.RepoOwner, x.RepoName)
+	branchURL := x.repoURL("/branches/main")
 	branchData, _, err := x.makeRequest(branchURL, "")
 	if err != nil {
 		return nil, err
@@ -188,74 +216,101 @@ func (x *GHAPI) GetAllFilesFromMainBranch() ([]string, error) {
 	commitSHA := branchInfo["commit"].(map[string]interface{})["sha"].(string)
 
 	// Get the tree recursively
-	treeURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/git/trees/%s?recursive=1", x.RepoOwner, x.RepoName, commitSHA)
+	treeURL := x.repoURL("/git/trees/" + commitSHA + "?recursive=1")
 	treeData, _, err := x.makeRequest(treeURL, "")
 	if err != nil {
 		return nil, err
 	}
 
 	var treeResponse struct {
-		Tree []struct {
-			Path string `json:"path"`
-			Type string `json:"type"`
-			URL  string `json:"url"`
-		} `json:"tree"`
+		Tree []treeFile `json:"tree"`
 	}
 
 	if err := json.Unmarshal([]byte(treeData), &treeResponse); err != nil {
 		return nil, err
 	}
 
-	fileNames := make([]string, 0, len(treeResponse.Tree))
+	files := make([]treeFile, 0, len(treeResponse.Tree))
 	for _, item := range treeResponse.Tree {
 		if item.Type == "blob" { // Only files, not directories
-			fileNames = append(fileNames, item.Path)
+			files = append(files, item)
 		}
 	}
 
-	return fileNames, nil
+	return files, nil
 }
 
-func (x *GHAPI) ExtractFileData(commits []interface{}) (map[string]string, map[string]int, map[string]int, map[string]int, error) {
-	fileURLMap := make(map[string]string)
-	fileSizeMap := make(map[string]int)
-	numUseStateMap := make(map[string]int)
-	fileChangesMap := make(map[string]int)
+// rawFileURL
+// Gets the URL of the contents of a file on the main branch
+func (x *GHAPI) rawFileURL(fileName string) string {
+	return fmt.Sprintf("%s/%s/%s/main/%s", x.Hosts.Raw, x.RepoOwner, x.RepoName, fileName)
+}
 
-	for _, commit := range commits {
-		commitData, err := x.getCommitData(commit)
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
-		_files := commitData.(map[string]interface{})["files"].([]interface{})
+// FileURL
+// Gets the URL of the contents of a file on the main branch
+func (x *GHAPI) FileURL(fileName string) string {
+	return x.rawFileURL(fileName)
+}
 
-		for _, file := range _files {
-			_filename := file.(map[string]interface{})["filename"].(string)
-			_fileChanges := file.(map[string]interface{})["changes"].(float64)
-			fileChangesMap[_filename] += int(_fileChanges)
-		}
-	}
+// getCommits
+// Gets the commits of the main branch, newest first, see source
+func (x *GHAPI) getCommits(stop func(commit interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Commits"
+	return x.getPages(x.repoURL("/commits"), stop)
+}
+
+// commitRef
+// Gets a commit from its SHA alone, see source
+func (x *GHAPI) commitRef(sha string) interface{} {
+	return map[string]interface{}{"url": x.repoURL("/commits/" + sha)}
+}
+
+// getUpdatedPRs
+// Gets every PR, most recently updated first, see source
+func (x *GHAPI) getUpdatedPRs(stop func(pr interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Pull Requests"
+	return x.getPages(x.repoURL("/pulls?state=all&sort=updated&direction=desc"), stop)
+}
 
-	fileNames, err := x.GetAllFilesFromMainBranch()
+// repoURL
+// Gets the REST API URL of a path under the repository, ex: "/commits"
+func (x *GHAPI) repoURL(path string) string {
+	return fmt.Sprintf("%s/repos/%s/%s%s", x.Hosts.API, x.RepoOwner, x.RepoName, path)
+}
+
+// getFileStats
+// Downloads a file and counts its lines and useState calls
+func (x *GHAPI) getFileStats(fileName string) (int, int, error) {
+	fileContents, err := x.downloadFileContent(x.rawFileURL(fileName))
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return 0, 0, err
 	}
+	return numLines(fileContents), numUseStates(fileContents), nil
+}
 
-	for _, fileName := range fileNames {
-		fileUrl := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/main/%s",
-			x.RepoOwner, x.RepoName, fileName)
-		fileURLMap[fileName] = fileUrl
+// getCommitChanges
+// Gets the change to every file in a commit
+func (x *GHAPI) getCommitChanges(commit interface{}) ([]utils.FileChange, error) {
+	commitData, err := x.getCommitData(commit)
+	if err != nil {
+		return nil, err
 	}
+	_sha := commitData.(map[string]interface{})["sha"].(string)
+	_author := commitAuthorName(commitData)
+	_files := commitData.(map[string]interface{})["files"].([]interface{})
 
-	for file, url := range fileURLMap {
-		fileContents, err := x.downloadFileContent(url)
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
-		fileSizeMap[file] = numLines(fileContents)
-		// Subtract 1 to remove the import statement
-		numUseStateMap[file] = strings.Count(fileContents, "useState") - 1
+	changes := make([]utils.FileChange, 0, len(_files))
+	for _, file := range _files {
+		_filename := file.(map[string]interface{})["filename"].(string)
+		_fileChanges := file.(map[string]interface{})["changes"].(float64)
+		_status, _ := file.(map[string]interface{})["status"].(string)
+		_previousFilename, _ := file.(map[string]interface{})["previous_filename"].(string)
+		_additions, _ := file.(map[string]interface{})["additions"].(float64)
+		_deletions, _ := file.(map[string]interface{})["deletions"].(float64)
+		changes = append(changes, utils.FileChange{SHA: _sha, Author: _author,
+			Path: _filename, CommitPath: _filename, PreviousPath: _previousFilename,
+			Status: _status, Additions: int(_additions), Deletions: int(_deletions),
+			Changes: int(_fileChanges)})
 	}
-
-	return fileURLMap, fileSizeMap, fileChangesMap, numUseStateMap, nil
+	return changes, nil
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"regexp"
+	"repo_stats/utils"
 	"strings"
 )
 
@@ -35,3 +36,52 @@ func numLines(s string) int {
 	}
 	return n
 }
+
+// numUseStates
+// Gets the number of useState calls in a file, 0 for files without any
+func numUseStates(s string) int {
+	// Subtract 1 to remove the import statement
+	return max(strings.Count(s, "useState")-1, 0)
+}
+
+// commitAuthorName
+// Gets the author name of a commit in the format returned from GitHub API, which Stats replaces
+// by the author's login when the commit has one
+func commitAuthorName(commit interface{}) string {
+	_commit, ok := commit.(map[string]interface{})["commit"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	_author, ok := _commit["author"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	_name, _ := _author["name"].(string)
+	return _name
+}
+
+// followRenames
+// Sets the Path of every change to the current path of the file, following rename chains
+//
+// Parameters:
+//   - history: every file change, newest commit first as returned from GitHub API
+func followRenames(history []utils.FileChange) {
+	// Maps a path as of some commit to the path of the same file today
+	currentPaths := make(map[string]string)
+	for index := range history {
+		change := &history[index]
+		currentPath, ok := currentPaths[change.CommitPath]
+		if !ok {
+			currentPath = change.CommitPath
+		}
+		change.Path = currentPath
+
+		switch change.Status {
+		case "renamed":
+			currentPaths[change.PreviousPath] = currentPath
+		case "added":
+			// Older changes to this path belong to a different file
+			delete(currentPaths, change.CommitPath)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"repo_stats/utils"
+	"strconv"
+	"strings"
+)
+
+// giteaPageSize
+// The number of items requested per page, instances may return fewer
+const giteaPageSize = 50
+
+// GiteaAPI
+// Collects a repository on a Gitea or Forgejo instance through the REST API v1, whose PRs and
+// commits already share the format of GitHub API PRs and commits
+type GiteaAPI struct {
+	RepoOwner       string
+	RepoName        string
+	RequestCategory string
+	Verbose         bool
+	// The base URL of the instance, ex: https://codeberg.org or https://git.example.org
+	BaseURL       string
+	authToken     string
+	defaultBranch string
+}
+
+// NewGiteaAPI
+// Creates a new GiteaAPI, getting the default branch of the repository
+//
+// Parameters:
+//   - repoOwner: the owner of the repository
+//   - repoName: the name of the repository
+//   - authToken: an access token with read access to the repository, empty for public repositories
+//   - baseURL: the base URL of the instance
+//
+// Returns pointer to new GiteaAPI
+func NewGiteaAPI(repoOwner, repoName string, authToken string, baseURL string) *GiteaAPI {
+	api := &GiteaAPI{RepoOwner: repoOwner, RepoName: repoName, Verbose: true,
+		BaseURL: strings.TrimRight(baseURL, "/"), authToken: authToken, defaultBranch: "main"}
+	api.RequestCategory = "Repository"
+	body, _, err := api.makeRequest(api.repoURL(""))
+	if err == nil {
+		var repository struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if json.Unmarshal([]byte(body), &repository) == nil && repository.DefaultBranch != "" {
+			api.defaultBranch = repository.DefaultBranch
+		}
+	}
+	api.RequestCategory = ""
+	return api
+}
+
+// makeRequest
+// Makes a request to the Gitea API
+func (x *GiteaAPI) makeRequest(url string) (string, http.Header, error) {
+	if x.RequestCategory != "" && x.Verbose {
+		utils.OutputFrom([]string{"[" + x.GetRateLimitRemainingString() + "]",
+			x.RequestCategory, url},
+			[]utils.Color{utils.Highlight, utils.TitleNoBold, utils.Subtle})
+	}
+
+	headers := map[string]string{"Accept": "application/json"}
+	if x.authToken != "" {
+		headers["Authorization"] = "token " + x.authToken
+	}
+	return utils.Get(url, "", headers)
+}
+
+// GetRateLimitRemainingString
+// Gitea and Forgejo do not report a rate limit
+func (x *GiteaAPI) GetRateLimitRemainingString() string {
+	return "no limit"
+}
+
+// Refresh
+// Fetches activity since the last refresh and merges it into the dataset, the same way as
+// GHAPI.Refresh
+//
+// Parameters:
+//   - dataset: the dataset to refresh
+//
+// Returns any errors, the dataset is unchanged on error
+func (x *GiteaAPI) Refresh(dataset *Dataset) error {
+	return refreshDataset(x, dataset)
+}
+
+// FileURL
+// Gets the API URL of the contents of a file on the default branch
+func (x *GiteaAPI) FileURL(fileName string) string {
+	segments := strings.Split(fileName, "/")
+	for index, segment := range segments {
+		segments[index] = url.PathEscape(segment)
+	}
+	return x.repoURL("/raw/" + strings.Join(segments, "/") + "?ref=" + url.QueryEscape(x.defaultBranch))
+}
+
+// repoURL
+// Gets the API URL of a path under the repository, ex: "/commits"
+func (x *GiteaAPI) repoURL(path string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s%s", x.BaseURL, url.PathEscape(x.RepoOwner),
+		url.PathEscape(x.RepoName), path)
+}
+
+// getPages
+// Gets every item of a list paginated with the page and limit parameters, until the
+// X-Total-Count header is reached or a page is empty
+//
+// Parameters:
+//   - listURL: the list, with a query of its own
+//   - stop: called for each item in order, returning true stops before that item without
+//     requesting further pages, nil to get every item
+//
+// Returns every item before the stop
+func (x *GiteaAPI) getPages(listURL string, stop func(item interface{}) bool) ([]interface{}, error) {
+	items := make([]interface{}, 0)
+	for page := 1; ; page++ {
+		body, headers, err := x.makeRequest(fmt.Sprintf("%s&limit=%d&page=%d", listURL, giteaPageSize, page))
+		if err != nil {
+			return nil, err
+		}
+		parsedBody, err := utils.ParseBody(body)
+		if err != nil {
+			return nil, err
+		}
+		pageItems, ok := parsedBody.([]interface{})
+		if !ok {
+			return nil, utils.WrapError(errors.New("expected a list"), "getPages", listURL)
+		}
+		for _, item := range pageItems {
+			if stop != nil && stop(item) {
+				return items, nil
+			}
+			items = append(items, item)
+		}
+
+		total, err := strconv.Atoi(headers.Get("X-Total-Count"))
+		if len(pageItems) == 0 || (err == nil && len(items) >= total) {
+			return items, nil
+		}
+	}
+}
+
+// getCommits
+// Gets the commits of the default branch, newest first, see source
+func (x *GiteaAPI) getCommits(stop func(commit interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Commits"
+	return x.getPages(x.repoURL("/commits?stat=false&verification=false&files=false&sha="+
+		url.QueryEscape(x.defaultBranch)), stop)
+}
+
+// commitRef
+// Gets a commit from its SHA alone, see source
+func (x *GiteaAPI) commitRef(sha string) interface{} {
+	return map[string]interface{}{"sha": sha}
+}
+
+// getCommitChanges
+// Gets the change to every file in a commit from its diff, which has the line counts the
+// commit API leaves out
+func (x *GiteaAPI) getCommitChanges(commit interface{}) ([]utils.FileChange, error) {
+	x.RequestCategory = "Individual Commit"
+	_sha, _ := commit.(map[string]interface{})["sha"].(string)
+	_author := commitAuthorName(commit)
+	diff, _, err := x.makeRequest(x.repoURL("/git/commits/" + _sha + ".diff"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := parseGitDiff(diff)
+	changes := make([]utils.FileChange, 0, len(files))
+	for _, file := range files {
+		changes = append(changes, utils.FileChange{SHA: _sha, Author: _author, Path: file.path,
+			CommitPath: file.path, PreviousPath: file.previousPath, Status: file.status,
+			Additions: file.additions, Deletions: file.deletions, Changes: file.additions + file.deletions})
+	}
+	return changes, nil
+}
+
+// getUpdatedPRs
+// Gets every PR, most recently updated first, see source
+func (x *GiteaAPI) getUpdatedPRs(stop func(pr interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Pull Requests"
+	return x.getPages(x.repoURL("/pulls?state=all&sort=recentupdate"), stop)
+}
+
+// getMainTree
+// Gets every file, but not directory, in the tree of the latest commit on the default branch
+func (x *GiteaAPI) getMainTree() ([]treeFile, error) {
+	x.RequestCategory = "Tree"
+	branchData, _, err := x.makeRequest(x.repoURL("/branches/" + url.PathEscape(x.defaultBranch)))
+	if err != nil {
+		return nil, err
+	}
+	var branch struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	err = json.Unmarshal([]byte(branchData), &branch)
+	if err != nil {
+		return nil, utils.WrapError(err, "getMainTree", "while parsing branch")
+	}
+
+	// Trees are paginated by page, with the number of entries in total_count
+	files := make([]treeFile, 0)
+	for page, seen := 1, 0; ; page++ {
+		treeData, _, err := x.makeRequest(x.repoURL(fmt.Sprintf("/git/trees/%s?recursive=true&per_page=1000&page=%d",
+			branch.Commit.ID, page)))
+		if err != nil {
+			return nil, err
+		}
+		var treeResponse struct {
+			Tree       []treeFile `json:"tree"`
+			TotalCount int        `json:"total_count"`
+		}
+		err = json.Unmarshal([]byte(treeData), &treeResponse)
+		if err != nil {
+			return nil, utils.WrapError(err, "getMainTree", "while parsing tree")
+		}
+		for _, item := range treeResponse.Tree {
+			if item.Type == "blob" { // Only files, not directories
+				files = append(files, item)
+			}
+		}
+		seen += len(treeResponse.Tree)
+		if len(treeResponse.Tree) == 0 || seen >= treeResponse.TotalCount {
+			return files, nil
+		}
+	}
+}
+
+// getFileStats
+// Downloads a file and counts its lines and useState calls
+func (x *GiteaAPI) getFileStats(fileName string) (int, int, error) {
+	x.RequestCategory = "File"
+	fileContents, _, err := x.makeRequest(x.FileURL(fileName))
+	if err != nil {
+		return 0, 0, err
+	}
+	return numLines(fileContents), numUseStates(fileContents), nil
+}
+
+// diffFile
+// The change to a single file in a git diff
+type diffFile struct {
+	path         string
+	previousPath string
+	status       string
+	additions    int
+	deletions    int
+}
+
+// parseGitDiff
+// Parses the files changed by a git diff, with the statuses used by GitHub API
+func parseGitDiff(diff string) []diffFile {
+	files := make([]diffFile, 0)
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			// diff --git a/path b/path, renames are listed below
+			files = append(files, diffFile{path: diffHeaderPath(line), status: "modified"})
+			inHunk = false
+			continue
+		}
+		if len(files) == 0 {
+			continue
+		}
+		file := &files[len(files)-1]
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && strings.HasPrefix(line, "+"):
+			file.additions++
+		case inHunk && strings.HasPrefix(line, "-"):
+			file.deletions++
+		case inHunk:
+		case strings.HasPrefix(line, "new file mode"):
+			file.status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.status = "removed"
+		case strings.HasPrefix(line, "rename from "):
+			file.status, file.previousPath = "renamed", unquoteDiffPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.path = unquoteDiffPath(strings.TrimPrefix(line, "rename to "))
+		}
+	}
+	return files
+}
+
+// diffHeaderPath
+// Gets the new path of a file from its diff --git line, where git quotes paths with special
+// characters, ex: diff --git "a/caf\303\251.txt" "b/caf\303\251.txt"
+func diffHeaderPath(header string) string {
+	paths := strings.TrimPrefix(header, "diff --git ")
+	if strings.HasSuffix(paths, `"`) {
+		if index := strings.LastIndex(paths, ` "b/`); index >= 0 {
+			return strings.TrimPrefix(unquoteDiffPath(paths[index+1:]), "b/")
+		}
+	}
+	if index := strings.LastIndex(paths, " b/"); index >= 0 {
+		return paths[index+len(" b/"):]
+	}
+	return ""
+}
+
+// unquoteDiffPath
+// Gets a path of a git diff, which git quotes with C style escapes if it has special characters
+func unquoteDiffPath(path string) string {
+	if !strings.HasPrefix(path, `"`) {
+		return path
+	}
+	unquoted, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+	return unquoted
+}
+
+// IsGiteaURL
+// Checks if a repository URL is on codeberg.org or a host named like a Gitea or Forgejo instance,
+// ex: gitea.example.com
+func IsGiteaURL(repoURL string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	return host == "codeberg.org" || strings.HasPrefix(host, "gitea.") || strings.HasPrefix(host, "forgejo.")
+}
+
+// ParseGiteaURL
+// Parses a repository given as a full URL, ex: https://codeberg.org/owner/repo/src/branch/main
+//
+// Parameters:
+//   - repoURL: the URL of the repository
+//
+// Returns the base URL of the instance, the owner and the name of the repository
+func ParseGiteaURL(repoURL string) (string, string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil {
+		return "", "", "", utils.WrapError(err, "ParseGiteaURL", repoURL)
+	}
+	items := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if parsed.Host == "" || len(items) < 2 || items[0] == "" || items[1] == "" {
+		return "", "", "", utils.WrapError(errors.New("expected https://host/owner/repo"), "ParseGiteaURL", repoURL)
+	}
+	return parsed.Scheme + "://" + parsed.Host, items[0], strings.TrimSuffix(items[1], ".git"), nil
+}
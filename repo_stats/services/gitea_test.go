@@ -0,0 +1,279 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"repo_stats/utils"
+	"strings"
+	"testing"
+)
+
+// fakeResponse
+// A response served by fakeTransport
+type fakeResponse struct {
+	body   string
+	header http.Header
+}
+
+// fakeTransport
+// Serves a fixed response for each URL, 404 for any other, and records every URL requested
+type fakeTransport struct {
+	responses map[string]fakeResponse
+	requested []string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requested = append(f.requested, req.URL.String())
+	response, ok := f.responses[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	header := response.header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: header,
+		Body: io.NopCloser(strings.NewReader(response.body)), Request: req}, nil
+}
+
+// useFakeTransport
+// Sends every request of a test to a fakeTransport
+func useFakeTransport(t *testing.T, responses map[string]fakeResponse) *fakeTransport {
+	fake := &fakeTransport{responses: responses}
+	utils.SetTransport(fake)
+	t.Cleanup(func() { utils.SetTransport(nil) })
+	return fake
+}
+
+// newTestGiteaAPI
+// Creates a GiteaAPI for owner/repo on https://git.test without requesting the repository
+func newTestGiteaAPI() *GiteaAPI {
+	return &GiteaAPI{RepoOwner: "owner", RepoName: "repo", BaseURL: "https://git.test", defaultBranch: "main"}
+}
+
+func TestParseGitDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []diffFile
+	}{
+		{
+			name: "modified",
+			diff: "diff --git a/src/app.go b/src/app.go\n" +
+				"index 1111111..2222222 100644\n" +
+				"--- a/src/app.go\n" +
+				"+++ b/src/app.go\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" package main\n" +
+				"-var a = 1\n" +
+				"+var a = 2\n" +
+				"+var b = 3\n",
+			want: []diffFile{{path: "src/app.go", status: "modified", additions: 2, deletions: 1}},
+		},
+		{
+			name: "lines like file headers inside hunks",
+			diff: "diff --git a/notes.md b/notes.md\n" +
+				"--- a/notes.md\n" +
+				"+++ b/notes.md\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"--- old rule\n" +
+				"+++ new rule\n",
+			want: []diffFile{{path: "notes.md", status: "modified", additions: 1, deletions: 1}},
+		},
+		{
+			name: "added and removed",
+			diff: "diff --git a/new.txt b/new.txt\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ b/new.txt\n" +
+				"@@ -0,0 +1,2 @@\n" +
+				"+one\n" +
+				"+two\n" +
+				"diff --git a/old.txt b/old.txt\n" +
+				"deleted file mode 100644\n" +
+				"--- a/old.txt\n" +
+				"+++ /dev/null\n" +
+				"@@ -1 +0,0 @@\n" +
+				"-gone\n",
+			want: []diffFile{
+				{path: "new.txt", status: "added", additions: 2},
+				{path: "old.txt", status: "removed", deletions: 1},
+			},
+		},
+		{
+			name: "renamed",
+			diff: "diff --git a/before.go b/after.go\n" +
+				"similarity index 90%\n" +
+				"rename from before.go\n" +
+				"rename to after.go\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			want: []diffFile{{path: "after.go", previousPath: "before.go", status: "renamed", additions: 1, deletions: 1}},
+		},
+		{
+			name: "quoted paths",
+			diff: "diff --git \"a/my file.txt\" \"b/my file.txt\"\n" +
+				"@@ -1 +1 @@\n" +
+				"+x\n" +
+				"diff --git \"a/caf\\303\\251.txt\" \"b/caf\\303\\251.txt\"\n" +
+				"@@ -1 +1 @@\n" +
+				"-y\n",
+			want: []diffFile{
+				{path: "my file.txt", status: "modified", additions: 1},
+				{path: "café.txt", status: "modified", deletions: 1},
+			},
+		},
+		{
+			name: "quoted rename",
+			diff: "diff --git \"a/tab\\there.txt\" \"b/new \\\"name\\\".txt\"\n" +
+				"similarity index 100%\n" +
+				"rename from \"tab\\there.txt\"\n" +
+				"rename to \"new \\\"name\\\".txt\"\n",
+			want: []diffFile{{path: "new \"name\".txt", previousPath: "tab\there.txt", status: "renamed"}},
+		},
+		{
+			name: "empty",
+			diff: "",
+			want: []diffFile{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseGitDiff(test.diff)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseGitDiff() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGiteaGetPages(t *testing.T) {
+	listURL := "https://git.test/api/v1/repos/owner/repo/pulls?state=all"
+	pageURL := func(page string) string { return listURL + "&limit=50&page=" + page }
+	total := func(count string) http.Header { return http.Header{"X-Total-Count": {count}} }
+
+	tests := []struct {
+		name      string
+		responses map[string]fakeResponse
+		stop      func(item interface{}) bool
+		want      []interface{}
+		requested int
+	}{
+		{
+			name: "stops at the total count",
+			responses: map[string]fakeResponse{
+				pageURL("1"): {body: `[1, 2]`, header: total("3")},
+				pageURL("2"): {body: `[3]`, header: total("3")},
+			},
+			want:      []interface{}{1.0, 2.0, 3.0},
+			requested: 2,
+		},
+		{
+			name: "stops at an empty page without a total count",
+			responses: map[string]fakeResponse{
+				pageURL("1"): {body: `[1]`},
+				pageURL("2"): {body: `[]`},
+			},
+			want:      []interface{}{1.0},
+			requested: 2,
+		},
+		{
+			name: "stops before the item matched by stop",
+			responses: map[string]fakeResponse{
+				pageURL("1"): {body: `[1, 2]`, header: total("4")},
+				pageURL("2"): {body: `[3, 4]`, header: total("4")},
+			},
+			stop:      func(item interface{}) bool { return item == 2.0 },
+			want:      []interface{}{1.0},
+			requested: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := useFakeTransport(t, test.responses)
+			got, err := newTestGiteaAPI().getPages(listURL, test.stop)
+			if err != nil {
+				t.Fatalf("getPages() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getPages() = %v, want %v", got, test.want)
+			}
+			if len(fake.requested) != test.requested {
+				t.Errorf("getPages() requested %v, want %d pages", fake.requested, test.requested)
+			}
+		})
+	}
+
+	t.Run("rejects a page which is not a list", func(t *testing.T) {
+		useFakeTransport(t, map[string]fakeResponse{pageURL("1"): {body: `{"message": "not found"}`}})
+		_, err := newTestGiteaAPI().getPages(listURL, nil)
+		if err == nil {
+			t.Error("getPages() error = nil, want an error")
+		}
+	})
+}
+
+func TestGiteaGetMainTree(t *testing.T) {
+	branchURL := "https://git.test/api/v1/repos/owner/repo/branches/main"
+	treeURL := "https://git.test/api/v1/repos/owner/repo/git/trees/abc123?recursive=true&per_page=1000&page="
+
+	tests := []struct {
+		name      string
+		responses map[string]fakeResponse
+		want      []treeFile
+		wantErr   bool
+	}{
+		{
+			name: "only files, across pages",
+			responses: map[string]fakeResponse{
+				branchURL: {body: `{"commit": {"id": "abc123"}}`},
+				treeURL + "1": {body: `{"total_count": 3, "tree": [
+					{"path": "src", "type": "tree", "sha": "t1"},
+					{"path": "src/a.go", "type": "blob", "sha": "b1"}]}`},
+				treeURL + "2": {body: `{"total_count": 3, "tree": [
+					{"path": "README.md", "type": "blob", "sha": "b2"}]}`},
+			},
+			want: []treeFile{{Path: "src/a.go", Type: "blob", SHA: "b1"}, {Path: "README.md", Type: "blob", SHA: "b2"}},
+		},
+		{
+			name: "stops at an empty page",
+			responses: map[string]fakeResponse{
+				branchURL:     {body: `{"commit": {"id": "abc123"}}`},
+				treeURL + "1": {body: `{"total_count": 5, "tree": [{"path": "a", "type": "blob", "sha": "b1"}]}`},
+				treeURL + "2": {body: `{"total_count": 5, "tree": []}`},
+			},
+			want: []treeFile{{Path: "a", Type: "blob", SHA: "b1"}},
+		},
+		{
+			name:      "missing branch",
+			responses: map[string]fakeResponse{},
+			wantErr:   true,
+		},
+		{
+			name: "invalid tree",
+			responses: map[string]fakeResponse{
+				branchURL:     {body: `{"commit": {"id": "abc123"}}`},
+				treeURL + "1": {body: `not json`},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useFakeTransport(t, test.responses)
+			got, err := newTestGiteaAPI().getMainTree()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("getMainTree() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getMainTree() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"repo_stats/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin
+// How long before it expires an installation token is replaced, so a token never expires during
+// a request
+const tokenRefreshMargin = 5 * time.Minute
+
+// GitHubApp
+// Authenticates as an installation of a GitHub App, as an alternative to a personal access token.
+// Installation tokens last an hour and are refreshed automatically before they expire
+type GitHubApp struct {
+	AppID string
+	// The installation to authenticate as, 0 to look up the installation of each repository's owner
+	InstallationID int64
+	// The REST API base URL of the GitHub instance the app is on
+	APIURL        string
+	privateKey    *rsa.PrivateKey
+	mutex         sync.Mutex
+	installations map[string]int64
+	tokens        map[int64]installationToken
+}
+
+// installationToken
+// An installation access token along with when it expires
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewGitHubApp
+// Creates a new GitHubApp
+//
+// Parameters:
+//   - appID: the ID of the app, shown on its settings page
+//   - privateKeyPEM: a private key of the app, as downloaded from its settings page
+//   - installationID: the installation to authenticate as, 0 to look up the installation of
+//     each repository's owner
+//   - apiURL: the REST API base URL, ex: https://api.github.com
+//
+// Returns pointer to new GitHubApp
+func NewGitHubApp(appID string, privateKeyPEM []byte, installationID int64, apiURL string) (*GitHubApp, error) {
+	if appID == "" {
+		return nil, utils.WrapError(errors.New("no app ID"), "NewGitHubApp", "")
+	}
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, utils.WrapError(errors.New("no PEM data"), "NewGitHubApp", "while reading private key")
+	}
+
+	// GitHub gives PKCS #1 keys, PKCS #8 is accepted for keys converted by other tools
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsedKey, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsedKey.(*rsa.PrivateKey)
+		if pkcs8Err != nil || !ok {
+			return nil, utils.WrapError(err, "NewGitHubApp", "while parsing private key")
+		}
+		privateKey = rsaKey
+	}
+	return &GitHubApp{AppID: appID, InstallationID: installationID, APIURL: strings.TrimRight(apiURL, "/"),
+		privateKey: privateKey, installations: make(map[string]int64),
+		tokens: make(map[int64]installationToken)}, nil
+}
+
+// LoadGitHubApp
+// Creates a new GitHubApp with its private key given inline or read from a file
+//
+// Parameters:
+//   - appID: the ID of the app
+//   - privateKey: a private key of the app, either the PEM itself or the path of its .pem file
+//   - installationID: the installation to authenticate as, empty to look up the installation
+//     of each repository's owner
+//   - apiURL: the REST API base URL
+//
+// Returns pointer to new GitHubApp
+func LoadGitHubApp(appID string, privateKey string, installationID string, apiURL string) (*GitHubApp, error) {
+	privateKeyPEM := []byte(strings.TrimSpace(privateKey))
+	var err error
+	if !strings.HasPrefix(string(privateKeyPEM), "-----BEGIN") {
+		privateKeyPEM, err = os.ReadFile(privateKey)
+		if err != nil {
+			return nil, utils.WrapError(err, "LoadGitHubApp", "while reading "+privateKey)
+		}
+	}
+	var id int64
+	if installationID != "" {
+		id, err = strconv.ParseInt(installationID, 10, 64)
+		if err != nil {
+			return nil, utils.WrapError(err, "LoadGitHubApp", "while parsing installation ID")
+		}
+	}
+	return NewGitHubApp(appID, privateKeyPEM, id, apiURL)
+}
+
+// JWT
+// Creates a JSON Web Token authenticating as the app itself, signed with RS256 and valid for
+// about ten minutes
+func (a *GitHubApp) JWT() (string, error) {
+	now := time.Now()
+	header := `{"alg":"RS256","typ":"JWT"}`
+	// Issued a minute early in case the clock is ahead of GitHub's, which allows up to ten minutes
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", utils.WrapError(err, "JWT", "while encoding claims")
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", utils.WrapError(err, "JWT", "while signing")
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Token
+// Gets an installation access token with access to a repository, reusing the last one until
+// shortly before it expires
+//
+// Parameters:
+//   - repoOwner: the owner of the repository
+//   - repoName: the name of the repository
+//
+// Returns the token
+func (a *GitHubApp) Token(repoOwner string, repoName string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	installationID, err := a.installationID(repoOwner, repoName)
+	if err != nil {
+		return "", err
+	}
+	token, ok := a.tokens[installationID]
+	if ok && time.Until(token.ExpiresAt) > tokenRefreshMargin {
+		return token.Token, nil
+	}
+
+	jwt, err := a.JWT()
+	if err != nil {
+		return "", err
+	}
+	body, _, err := utils.Post(a.APIURL+"/app/installations/"+strconv.FormatInt(installationID, 10)+"/access_tokens",
+		"", a.headers(jwt))
+	if err != nil {
+		return "", err
+	}
+	token = installationToken{}
+	err = json.Unmarshal([]byte(body), &token)
+	if err != nil || token.Token == "" {
+		return "", utils.WrapError(errors.New(responseMessage(body)), "Token", "while creating installation token")
+	}
+	a.tokens[installationID] = token
+	return token.Token, nil
+}
+
+// installationID
+// Gets the installation of the app with access to a repository, looked up once per owner
+func (a *GitHubApp) installationID(repoOwner string, repoName string) (int64, error) {
+	if a.InstallationID != 0 {
+		return a.InstallationID, nil
+	}
+	if id, ok := a.installations[repoOwner]; ok {
+		return id, nil
+	}
+
+	jwt, err := a.JWT()
+	if err != nil {
+		return 0, err
+	}
+	body, _, err := utils.Get(a.APIURL+"/repos/"+repoOwner+"/"+repoName+"/installation", "", a.headers(jwt))
+	if err != nil {
+		return 0, utils.WrapError(err, "installationID", "is the app installed on "+repoOwner+"?")
+	}
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	err = json.Unmarshal([]byte(body), &installation)
+	if err != nil || installation.ID == 0 {
+		return 0, utils.WrapError(errors.New(responseMessage(body)), "installationID", "while looking up installation")
+	}
+	a.installations[repoOwner] = installation.ID
+	return installation.ID, nil
+}
+
+// headers
+// Gets the headers of a request authenticated as the app itself
+func (a *GitHubApp) headers(jwt string) map[string]string {
+	return map[string]string{
+		"Accept":        "application/vnd.github+json",
+		"Authorization": "Bearer " + jwt,
+	}
+}
+
+// responseMessage
+// Gets the message of a GitHub API error response, or the whole response if it has none
+func responseMessage(body string) string {
+	var response struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &response) == nil && response.Message != "" {
+		return response.Message
+	}
+	return "unexpected response: " + body
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"repo_stats/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGitLabURL
+// The base URL of gitlab.com
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GLAPI
+// Collects a GitLab project through the REST API v4, converting merge requests and commits to
+// the format of GitHub API PRs and commits so they go through the same Stats as a GitHub
+// repository
+type GLAPI struct {
+	// The namespace of the project, which may include subgroups, ex: group/subgroup
+	RepoOwner       string
+	RepoName        string
+	RequestCategory string
+	Verbose         bool
+	// The base URL of the GitLab instance, ex: https://gitlab.com or https://gitlab.example.com
+	BaseURL            string
+	rateLimitRemaining int
+	rateLimitReset     time.Time
+	authToken          string
+	defaultBranch      string
+}
+
+// NewGLAPI
+// Creates a new GLAPI, getting the default branch of the project
+//
+// Parameters:
+//   - repoOwner: the namespace of the project, ex: group or group/subgroup
+//   - repoName: the name of the project
+//   - authToken: a personal, group or project access token, empty for public projects
+//   - baseURL: the base URL of the GitLab instance, empty for gitlab.com
+//
+// Returns pointer to new GLAPI
+func NewGLAPI(repoOwner, repoName string, authToken string, baseURL string) *GLAPI {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	api := &GLAPI{RepoOwner: repoOwner, RepoName: repoName, Verbose: true, BaseURL: strings.TrimRight(baseURL, "/"),
+		rateLimitRemaining: 2000, authToken: authToken, defaultBranch: "main"}
+	api.RequestCategory = "Project"
+	// Also sets the rate limit given the response header
+	body, _, err := api.makeRequest(api.projectURL(""))
+	if err == nil {
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if json.Unmarshal([]byte(body), &project) == nil && project.DefaultBranch != "" {
+			api.defaultBranch = project.DefaultBranch
+		}
+	}
+	api.RequestCategory = ""
+	return api
+}
+
+// makeRequest
+// Makes a request to the GitLab API, waiting for the rate limit to reset if it was hit
+func (x *GLAPI) makeRequest(url string) (string, http.Header, error) {
+	if x.RequestCategory != "" && x.Verbose {
+		utils.OutputFrom([]string{"[" + x.GetRateLimitRemainingString() + "]",
+			x.RequestCategory, url},
+			[]utils.Color{utils.Highlight, utils.TitleNoBold, utils.Subtle})
+	}
+
+	if x.rateLimitRemaining == 0 {
+		// If we have hit the rate limit, wait for the rest
+		timeToWait := x.rateLimitReset.Sub(time.Now())
+		utils.OutputFrom([]string{"Rate Limit Hit:", "Waiting For",
+			timeToWait.Round(time.Second).String()},
+			[]utils.Color{utils.Err, utils.Subtle, utils.Subtle})
+		time.Sleep(timeToWait)
+	}
+
+	headers := map[string]string{"Accept": "application/json"}
+	if x.authToken != "" {
+		headers["PRIVATE-TOKEN"] = x.authToken
+	}
+	respBody, header, err := utils.Get(url, "", headers)
+	if err != nil {
+		return "", nil, err
+	}
+	convertedLimit, err := strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if err == nil {
+		x.rateLimitRemaining = convertedLimit
+	}
+	if x.rateLimitRemaining == 0 {
+		convertedReset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return "", nil, err
+		}
+		x.rateLimitReset = time.Unix(convertedReset, 0)
+	}
+	return respBody, header, nil
+}
+
+// GetRateLimitRemainingString
+// Gets the number of requests left before the rate limit
+func (x *GLAPI) GetRateLimitRemainingString() string {
+	return strconv.Itoa(x.rateLimitRemaining)
+}
+
+// Refresh
+// Fetches activity since the last refresh and merges it into the dataset, the same way as
+// GHAPI.Refresh, with merge requests as PRs
+//
+// Parameters:
+//   - dataset: the dataset to refresh
+//
+// Returns any errors, the dataset is unchanged on error
+func (x *GLAPI) Refresh(dataset *Dataset) error {
+	return refreshDataset(x, dataset)
+}
+
+// FileURL
+// Gets the API URL of the contents of a file on the default branch
+func (x *GLAPI) FileURL(fileName string) string {
+	return x.projectURL("/repository/files/" + url.PathEscape(fileName) + "/raw?ref=" +
+		url.QueryEscape(x.defaultBranch))
+}
+
+// projectURL
+// Gets the API URL of a path under the project, ex: "/repository/commits"
+func (x *GLAPI) projectURL(path string) string {
+	return x.BaseURL + "/api/v4/projects/" + url.PathEscape(x.RepoOwner+"/"+x.RepoName) + path
+}
+
+// getPages
+// Gets every item of a paginated list, following the Link header of both keyset and offset
+// pagination, or the X-Next-Page header when the Link header is left out
+//
+// Parameters:
+//   - pageURL: the first page of the list
+//   - stop: called for each item in order, returning true stops before that item without
+//     requesting further pages, nil to get every item
+//
+// Returns every item before the stop
+func (x *GLAPI) getPages(pageURL string, stop func(item interface{}) bool) ([]interface{}, error) {
+	items := make([]interface{}, 0)
+	for pageURL != "" {
+		body, headers, err := x.makeRequest(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		parsedBody, err := utils.ParseBody(body)
+		if err != nil {
+			return nil, err
+		}
+		page, ok := parsedBody.([]interface{})
+		if !ok {
+			return nil, utils.WrapError(errors.New("expected a list"), "getPages", pageURL)
+		}
+		for _, item := range page {
+			if stop != nil && stop(item) {
+				return items, nil
+			}
+			items = append(items, item)
+		}
+
+		nextURL := parseNextLinkRegex(headers.Get("Link"))
+		if nextPage := headers.Get("X-Next-Page"); nextURL == "" && nextPage != "" {
+			parsed, err := url.Parse(pageURL)
+			if err != nil {
+				return nil, utils.WrapError(err, "getPages", pageURL)
+			}
+			query := parsed.Query()
+			query.Set("page", nextPage)
+			parsed.RawQuery = query.Encode()
+			nextURL = parsed.String()
+		}
+		pageURL = nextURL
+	}
+	return items, nil
+}
+
+// getCommits
+// Gets the commits of the default branch, newest first, see source
+func (x *GLAPI) getCommits(stop func(commit interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Commits"
+	commits := make([]interface{}, 0)
+	_, err := x.getPages(x.projectURL("/repository/commits?per_page=100&ref_name="+url.QueryEscape(x.defaultBranch)),
+		func(item interface{}) bool {
+			commit := convertGitLabCommit(item.(map[string]interface{}))
+			if stop != nil && stop(commit) {
+				return true
+			}
+			commits = append(commits, commit)
+			return false
+		})
+	return commits, err
+}
+
+// commitRef
+// Gets a commit from its SHA alone, see source
+func (x *GLAPI) commitRef(sha string) interface{} {
+	return map[string]interface{}{"sha": sha}
+}
+
+// getCommitChanges
+// Gets the change to every file in a commit, counting added and removed lines of its diff
+func (x *GLAPI) getCommitChanges(commit interface{}) ([]utils.FileChange, error) {
+	x.RequestCategory = "Individual Commit"
+	_sha, _ := commit.(map[string]interface{})["sha"].(string)
+	_author := commitAuthorName(commit)
+	diffs, err := x.getPages(x.projectURL("/repository/commits/"+_sha+"/diff?per_page=100"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]utils.FileChange, 0, len(diffs))
+	for _, item := range diffs {
+		diff := item.(map[string]interface{})
+		newPath, _ := diff["new_path"].(string)
+		oldPath, _ := diff["old_path"].(string)
+		text, _ := diff["diff"].(string)
+		additions, deletions := countDiffLines(text)
+
+		change := utils.FileChange{SHA: _sha, Author: _author, Path: newPath, CommitPath: newPath,
+			Status: "modified", Additions: additions, Deletions: deletions, Changes: additions + deletions}
+		if newFile, _ := diff["new_file"].(bool); newFile {
+			change.Status = "added"
+		} else if deleted, _ := diff["deleted_file"].(bool); deleted {
+			change.Status = "removed"
+		} else if renamed, _ := diff["renamed_file"].(bool); renamed {
+			change.Status, change.PreviousPath = "renamed", oldPath
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// getUpdatedPRs
+// Gets every merge request, most recently updated first, as GitHub API PRs, see source
+func (x *GLAPI) getUpdatedPRs(stop func(pr interface{}) bool) ([]interface{}, error) {
+	x.RequestCategory = "Merge Requests"
+	prs := make([]interface{}, 0)
+	_, err := x.getPages(x.projectURL("/merge_requests?state=all&order_by=updated_at&sort=desc&per_page=100"),
+		func(item interface{}) bool {
+			pr := convertGitLabMergeRequest(item.(map[string]interface{}))
+			if stop != nil && stop(pr) {
+				return true
+			}
+			prs = append(prs, pr)
+			return false
+		})
+	return prs, err
+}
+
+// getMainTree
+// Gets every file, but not directory, in the tree of the default branch, using keyset pagination
+func (x *GLAPI) getMainTree() ([]treeFile, error) {
+	x.RequestCategory = "Tree"
+	items, err := x.getPages(x.projectURL("/repository/tree?recursive=true&per_page=100&pagination=keyset&ref="+
+		url.QueryEscape(x.defaultBranch)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]treeFile, 0, len(items))
+	for _, item := range items {
+		entry := item.(map[string]interface{})
+		if entry["type"] != "blob" {
+			continue
+		}
+		path, _ := entry["path"].(string)
+		id, _ := entry["id"].(string)
+		files = append(files, treeFile{Path: path, Type: "blob", SHA: id})
+	}
+	return files, nil
+}
+
+// getFileStats
+// Downloads a file and counts its lines and useState calls
+func (x *GLAPI) getFileStats(fileName string) (int, int, error) {
+	x.RequestCategory = "File"
+	fileContents, _, err := x.makeRequest(x.FileURL(fileName))
+	if err != nil {
+		return 0, 0, err
+	}
+	return numLines(fileContents), numUseStates(fileContents), nil
+}
+
+// convertGitLabCommit
+// Converts a GitLab commit to the format of GitHub API commits
+func convertGitLabCommit(commit map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"sha":      commit["id"],
+		"html_url": commit["web_url"],
+		"commit": map[string]interface{}{
+			"message": commit["message"],
+			"author": map[string]interface{}{"name": commit["author_name"], "email": commit["author_email"],
+				"date": commit["authored_date"]},
+		},
+	}
+}
+
+// convertGitLabMergeRequest
+// Converts a GitLab merge request to the format of GitHub API PRs, numbered by its IID
+func convertGitLabMergeRequest(mergeRequest map[string]interface{}) map[string]interface{} {
+	author, _ := mergeRequest["author"].(map[string]interface{})
+	return map[string]interface{}{
+		"number":     mergeRequest["iid"],
+		"title":      mergeRequest["title"],
+		"state":      mergeRequest["state"],
+		"html_url":   mergeRequest["web_url"],
+		"created_at": mergeRequest["created_at"],
+		"updated_at": mergeRequest["updated_at"],
+		"merged_at":  mergeRequest["merged_at"],
+		"user":       map[string]interface{}{"login": author["username"]},
+	}
+}
+
+// countDiffLines
+// Counts the added and removed lines of a unified diff
+func countDiffLines(diff string) (int, int) {
+	additions, deletions := 0, 0
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk:
+			// File headers such as "--- a/path", which GitLab leaves out of most diffs. Inside a
+			// hunk, "--- " is a removed line starting with "--", such as an SQL comment
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions
+}
+
+// IsGitLabURL
+// Checks if a repository URL is on gitlab.com or a host named like a GitLab instance, ex:
+// gitlab.example.com
+func IsGitLabURL(repoURL string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	return host == "gitlab.com" || strings.HasPrefix(host, "gitlab.")
+}
+
+// ParseGitLabURL
+// Parses a project given as a full URL, ex: https://gitlab.example.com/group/subgroup/project
+//
+// Parameters:
+//   - projectURL: the URL of the project
+//
+// Returns the base URL of the instance, the namespace and the name of the project
+func ParseGitLabURL(projectURL string) (string, string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(projectURL))
+	if err != nil {
+		return "", "", "", utils.WrapError(err, "ParseGitLabURL", projectURL)
+	}
+	// Pages of a project, ex: /group/project/-/merge_requests
+	path, _, _ := strings.Cut(strings.Trim(parsed.Path, "/"), "/-/")
+	path = strings.TrimSuffix(path, ".git")
+	index := strings.LastIndex(path, "/")
+	if parsed.Host == "" || index <= 0 || index == len(path)-1 {
+		return "", "", "", utils.WrapError(errors.New("expected https://host/namespace/project"),
+			"ParseGitLabURL", projectURL)
+	}
+	return fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host), path[:index], path[index+1:], nil
+}
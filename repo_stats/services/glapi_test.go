@@ -0,0 +1,128 @@
+package services
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseGitLabURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		base      string
+		namespace string
+		project   string
+		wantErr   bool
+	}{
+		{url: "https://gitlab.com/group/project", base: "https://gitlab.com", namespace: "group", project: "project"},
+		{url: "https://gitlab.example.com/group/sub/deeper/project.git", base: "https://gitlab.example.com",
+			namespace: "group/sub/deeper", project: "project"},
+		{url: "https://gitlab.com/group/sub/project/-/merge_requests/3", base: "https://gitlab.com",
+			namespace: "group/sub", project: "project"},
+		{url: " http://gitlab.local:8080/group/project/ ", base: "http://gitlab.local:8080", namespace: "group",
+			project: "project"},
+		{url: "https://gitlab.com/project", wantErr: true},
+		{url: "https://gitlab.com/group/", wantErr: true},
+		{url: "gitlab.com/group/project", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			base, namespace, project, err := ParseGitLabURL(test.url)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseGitLabURL() error = %v, want error %v", err, test.wantErr)
+			}
+			if base != test.base || namespace != test.namespace || project != test.project {
+				t.Errorf("ParseGitLabURL() = %q, %q, %q, want %q, %q, %q", base, namespace, project,
+					test.base, test.namespace, test.project)
+			}
+		})
+	}
+}
+
+func TestCountDiffLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		diff      string
+		additions int
+		deletions int
+	}{
+		{name: "hunk", diff: "@@ -1,2 +1,2 @@\n context\n-old\n+new\n+more\n", additions: 2, deletions: 1},
+		{name: "file headers", diff: "--- a/x.sql\n+++ b/x.sql\n@@ -1 +1 @@\n--- comment\n+++ x\n",
+			additions: 1, deletions: 1},
+		{name: "empty", diff: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			additions, deletions := countDiffLines(test.diff)
+			if additions != test.additions || deletions != test.deletions {
+				t.Errorf("countDiffLines() = %d, %d, want %d, %d", additions, deletions, test.additions,
+					test.deletions)
+			}
+		})
+	}
+}
+
+func TestGitLabGetPages(t *testing.T) {
+	api := &GLAPI{RepoOwner: "group/sub", RepoName: "project", BaseURL: "https://gitlab.test",
+		rateLimitRemaining: 2000, defaultBranch: "main"}
+	listURL := api.projectURL("/merge_requests?state=all&per_page=100")
+	if want := "https://gitlab.test/api/v4/projects/group%2Fsub%2Fproject/merge_requests?state=all&per_page=100"; listURL != want {
+		t.Fatalf("projectURL() = %q, want %q", listURL, want)
+	}
+	keysetURL := "https://gitlab.test/api/v4/projects/group%2Fsub%2Fproject/merge_requests?cursor=abc"
+	nextPageURL := "https://gitlab.test/api/v4/projects/group%2Fsub%2Fproject/merge_requests?page=2&per_page=100&state=all"
+
+	tests := []struct {
+		name      string
+		responses map[string]fakeResponse
+		stop      func(item interface{}) bool
+		want      []interface{}
+		requested int
+	}{
+		{
+			name: "follows the Link header",
+			responses: map[string]fakeResponse{
+				listURL:   {body: `[1, 2]`, header: http.Header{"Link": {`<` + keysetURL + `>; rel="next"`}}},
+				keysetURL: {body: `[3]`},
+			},
+			want:      []interface{}{1.0, 2.0, 3.0},
+			requested: 2,
+		},
+		{
+			name: "follows X-Next-Page without a Link header",
+			responses: map[string]fakeResponse{
+				listURL:     {body: `[1]`, header: http.Header{"X-Next-Page": {"2"}}},
+				nextPageURL: {body: `[2]`, header: http.Header{"X-Next-Page": {""}}},
+			},
+			want:      []interface{}{1.0, 2.0},
+			requested: 2,
+		},
+		{
+			name: "stops before the item matched by stop",
+			responses: map[string]fakeResponse{
+				listURL: {body: `[1, 2]`, header: http.Header{"X-Next-Page": {"2"}}},
+			},
+			stop:      func(item interface{}) bool { return item == 2.0 },
+			want:      []interface{}{1.0},
+			requested: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := useFakeTransport(t, test.responses)
+			got, err := api.getPages(listURL, test.stop)
+			if err != nil {
+				t.Fatalf("getPages() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("getPages() = %v, want %v", got, test.want)
+			}
+			if len(fake.requested) != test.requested {
+				t.Errorf("getPages() requested %v, want %d pages", fake.requested, test.requested)
+			}
+		})
+	}
+}
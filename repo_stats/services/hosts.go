@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"repo_stats/utils"
+	"strings"
+)
+
+// GitHubHosts
+// The base URLs of a GitHub instance, either github.com or a GitHub Enterprise Server
+type GitHubHosts struct {
+	// The REST API, ex: https://api.github.com or https://github.example.com/api/v3
+	API string
+	// The GraphQL API, ex: https://api.github.com/graphql or https://github.example.com/api/graphql
+	GraphQL string
+	// The raw contents of files, followed by /owner/repo/branch/path, ex:
+	// https://raw.githubusercontent.com or https://github.example.com/raw
+	Raw string
+}
+
+// DefaultGitHubHosts
+// Returns the GitHubHosts of github.com
+func DefaultGitHubHosts() GitHubHosts {
+	return GitHubHosts{API: "https://api.github.com", GraphQL: "https://api.github.com/graphql",
+		Raw: "https://raw.githubusercontent.com"}
+}
+
+// EnterpriseHosts
+// Gets the GitHubHosts of a GitHub Enterprise Server, which serves the REST API under /api/v3,
+// the GraphQL API at /api/graphql and raw contents under /raw
+//
+// Parameters:
+//   - host: the hostname of the server, ex: github.example.com, https is used unless another
+//     scheme is given, ex: http://github.example.com
+//
+// Returns the GitHubHosts of the server, github.com's if host is github.com
+func EnterpriseHosts(host string) GitHubHosts {
+	base := strings.TrimRight(strings.TrimSpace(host), "/")
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	if hostname := strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://"); hostname == "github.com" ||
+		hostname == "www.github.com" {
+		return DefaultGitHubHosts()
+	}
+	return GitHubHosts{API: base + "/api/v3", GraphQL: base + "/api/graphql", Raw: base + "/raw"}
+}
+
+// WithOverrides
+// Replaces any of the base URLs, ignoring empty ones
+//
+// Parameters:
+//   - api: the REST API base URL
+//   - graphQL: the GraphQL API URL
+//   - raw: the raw contents base URL
+//
+// Returns the resulting GitHubHosts
+func (h GitHubHosts) WithOverrides(api string, graphQL string, raw string) GitHubHosts {
+	if api != "" {
+		h.API = strings.TrimRight(api, "/")
+	}
+	if graphQL != "" {
+		h.GraphQL = strings.TrimRight(graphQL, "/")
+	}
+	if raw != "" {
+		h.Raw = strings.TrimRight(raw, "/")
+	}
+	return h
+}
+
+// ParseRepoURL
+// Parses a repository given as a full URL, inferring the GitHub instance from its host, ex:
+// https://github.example.com/owner/repo or https://github.com/owner/repo.git
+//
+// Parameters:
+//   - repoURL: the URL of the repository
+//
+// Returns the GitHubHosts of the instance, the owner and the name of the repository
+func ParseRepoURL(repoURL string) (GitHubHosts, string, string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(repoURL))
+	if err != nil {
+		return GitHubHosts{}, "", "", utils.WrapError(err, "ParseRepoURL", repoURL)
+	}
+	items := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if parsed.Host == "" || len(items) < 2 || items[0] == "" || items[1] == "" {
+		return GitHubHosts{}, "", "", utils.WrapError(errors.New("expected https://host/owner/repo"),
+			"ParseRepoURL", repoURL)
+	}
+	return EnterpriseHosts(parsed.Scheme + "://" + parsed.Host), items[0], strings.TrimSuffix(items[1], ".git"), nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import "testing"
+
+func TestEnterpriseHosts(t *testing.T) {
+	tests := []struct {
+		host string
+		want GitHubHosts
+	}{
+		{host: "github.com", want: DefaultGitHubHosts()},
+		{host: "https://www.github.com/", want: DefaultGitHubHosts()},
+		{host: "github.example.com", want: GitHubHosts{API: "https://github.example.com/api/v3",
+			GraphQL: "https://github.example.com/api/graphql", Raw: "https://github.example.com/raw"}},
+		{host: "http://ghe.local/", want: GitHubHosts{API: "http://ghe.local/api/v3",
+			GraphQL: "http://ghe.local/api/graphql", Raw: "http://ghe.local/raw"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if got := EnterpriseHosts(test.host); got != test.want {
+				t.Errorf("EnterpriseHosts() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGitHubHostsWithOverrides(t *testing.T) {
+	hosts := EnterpriseHosts("github.example.com")
+	got := hosts.WithOverrides("", "https://graphql.example.com/", "https://raw.github.example.com/")
+	want := GitHubHosts{API: "https://github.example.com/api/v3", GraphQL: "https://graphql.example.com",
+		Raw: "https://raw.github.example.com"}
+	if got != want {
+		t.Errorf("WithOverrides() = %+v, want %+v", got, want)
+	}
+	if got := hosts.WithOverrides("", "", ""); got != hosts {
+		t.Errorf("WithOverrides() with no overrides = %+v, want %+v", got, hosts)
+	}
+}
+
+func TestParseRepoURL(t *testing.T) {
+	hosts, owner, name, err := ParseRepoURL("https://github.example.com/ctc-uci/project.git")
+	if err != nil || owner != "ctc-uci" || name != "project" || hosts != EnterpriseHosts("github.example.com") {
+		t.Errorf("ParseRepoURL() = %+v, %q, %q, %v", hosts, owner, name, err)
+	}
+	for _, repoURL := range []string{"github.example.com/ctc-uci", "https://github.com/ctc-uci"} {
+		if _, _, _, err := ParseRepoURL(repoURL); err == nil {
+			t.Errorf("ParseRepoURL(%q) error = nil, want an error", repoURL)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"repo_stats/utils"
+	"sort"
+	"strings"
+)
+
+// slackDayFileRegex
+// Matches the file of a single day of a channel, ex: 2024-03-05.json
+var slackDayFileRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\.json$`)
+
+// SlackExport
+// The users and channels of a Slack workspace export
+type SlackExport struct {
+	// Map of user ID to display name
+	Users map[string]string
+	// Every channel of the export, sorted by name
+	Channels []utils.SlackChannel
+}
+
+// ReadSlackExport
+// Reads a standard Slack workspace export, a ZIP with users.json and a directory per channel of
+// daily JSON files, ex: general/2024-03-05.json
+//
+// Parameters:
+//   - path: the ZIP file, or the directory it was extracted to
+//
+// Returns the users and channels of the export
+func ReadSlackExport(path string) (*SlackExport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, utils.WrapError(err, "ReadSlackExport", "while opening "+path)
+	}
+	if info.IsDir() {
+		return readSlackExport(os.DirFS(path))
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, utils.WrapError(err, "ReadSlackExport", "while opening "+path)
+	}
+	defer archive.Close()
+	return readSlackExport(archive)
+}
+
+// readSlackExport
+// Reads the users and channels of an export, which may be inside a single top level directory
+func readSlackExport(files fs.FS) (*SlackExport, error) {
+	_, err := fs.Stat(files, "users.json")
+	if err != nil {
+		root, rootErr := slackExportRoot(files)
+		if rootErr != nil {
+			return nil, rootErr
+		}
+		files, err = fs.Sub(files, root)
+		if err != nil {
+			return nil, utils.WrapError(err, "readSlackExport", "while opening "+root)
+		}
+	}
+
+	data, err := fs.ReadFile(files, "users.json")
+	if err != nil {
+		return nil, utils.WrapError(err, "readSlackExport", "while reading users.json")
+	}
+	var users []map[string]interface{}
+	err = json.Unmarshal(data, &users)
+	if err != nil {
+		return nil, utils.WrapError(err, "readSlackExport", "while parsing users.json")
+	}
+	export := &SlackExport{Users: make(map[string]string, len(users)), Channels: []utils.SlackChannel{}}
+	for _, user := range users {
+		id, _ := user["id"].(string)
+		export.Users[id] = slackUserName(user)
+	}
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, utils.WrapError(err, "readSlackExport", "while listing channels")
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), "__MACOSX") {
+			continue
+		}
+		channel, err := readSlackChannel(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(channel.Days) > 0 {
+			export.Channels = append(export.Channels, channel)
+		}
+	}
+	sort.Slice(export.Channels, func(i, j int) bool {
+		return export.Channels[i].Name < export.Channels[j].Name
+	})
+	return export, nil
+}
+
+// slackExportRoot
+// Finds the single top level directory containing users.json
+func slackExportRoot(files fs.FS) (string, error) {
+	matches, err := fs.Glob(files, "*/users.json")
+	if err != nil || len(matches) != 1 {
+		return "", utils.WrapError(errors.New("users.json not found"), "readSlackExport",
+			"expected a Slack workspace export")
+	}
+	return strings.TrimSuffix(matches[0], "/users.json"), nil
+}
+
+// readSlackChannel
+// Reads every daily file of a channel directory
+func readSlackChannel(files fs.FS, name string) (utils.SlackChannel, error) {
+	channel := utils.SlackChannel{Name: name, Days: make(map[string][]utils.SlackMessage)}
+	entries, err := fs.ReadDir(files, name)
+	if err != nil {
+		return channel, utils.WrapError(err, "readSlackChannel", "while listing "+name)
+	}
+	for _, entry := range entries {
+		match := slackDayFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		data, err := fs.ReadFile(files, name+"/"+entry.Name())
+		if err != nil {
+			return channel, utils.WrapError(err, "readSlackChannel", "while reading "+name+"/"+entry.Name())
+		}
+		var messages []utils.SlackMessage
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			return channel, utils.WrapError(err, "readSlackChannel", "while parsing "+name+"/"+entry.Name())
+		}
+		channel.Days[match[1]] = append(channel.Days[match[1]], messages...)
+	}
+	return channel, nil
+}
+
+// slackUserName
+// Gets the name a user is displayed by, preferring their real name over their username
+func slackUserName(user map[string]interface{}) string {
+	profile, _ := user["profile"].(map[string]interface{})
+	for _, name := range []interface{}{profile["real_name"], user["real_name"], profile["display_name"],
+		user["name"], user["id"]} {
+		if text, ok := name.(string); ok && strings.TrimSpace(text) != "" {
+			return strings.TrimSpace(text)
+		}
+	}
+	return ""
+}
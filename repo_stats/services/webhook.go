@@ -0,0 +1,248 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"repo_stats/utils"
+	"strings"
+)
+
+// VerifyWebhookSignature
+// Checks the X-Hub-Signature-256 header of a webhook delivery against the webhook secret
+//
+// Parameters:
+//   - secret: the secret set on the webhook
+//   - body: the raw body of the delivery
+//   - signature: the X-Hub-Signature-256 header, ex: "sha256=6f1c..."
+//
+// Returns true if the signature matches
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal(expected, webhookMAC(secret, body))
+}
+
+// SignWebhook
+// Gets the X-Hub-Signature-256 header GitHub sends with a delivery, used to replay deliveries
+//
+// Parameters:
+//   - secret: the secret set on the webhook
+//   - body: the raw body of the delivery
+//
+// Returns the header value
+func SignWebhook(secret string, body []byte) string {
+	return "sha256=" + hex.EncodeToString(webhookMAC(secret, body))
+}
+
+// webhookMAC
+// Gets the HMAC-SHA256 of a delivery body
+func webhookMAC(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// WebhookRepo
+// Gets the repository a webhook delivery is for
+//
+// Parameters:
+//   - payload: the raw body of the delivery
+//
+// Returns the owner and name of the repository
+func WebhookRepo(payload []byte) (string, string, error) {
+	var delivery struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	err := json.Unmarshal(payload, &delivery)
+	if err != nil {
+		return "", "", utils.WrapError(err, "WebhookRepo", "while parsing payload")
+	}
+	items := strings.SplitN(delivery.Repository.FullName, "/", 2)
+	if len(items) != 2 {
+		return "", "", utils.WrapError(errors.New("payload has no repository"), "WebhookRepo", "")
+	}
+	return items[0], items[1], nil
+}
+
+// ApplyWebhook
+// Updates the dataset from a webhook delivery without calling GitHub API:
+//   - push: commits to the default branch are added, with the files each commit added,
+//     modified and removed. Push payloads have no line counts, so the commits are kept as
+//     pending until Refresh or RefreshPending fetches their details. Changed files are
+//     downloaded again by the next Refresh
+//   - pull_request: the PR is added or updated
+//   - pull_request_review: the PR of the review is added or updated
+//
+// Parameters:
+//   - event: the X-GitHub-Event header of the delivery
+//   - payload: the raw body of the delivery
+//
+// Returns true if the dataset changed, false if the delivery was ignored
+func (d *Dataset) ApplyWebhook(event string, payload []byte) (bool, error) {
+	var delivery map[string]interface{}
+	err := json.Unmarshal(payload, &delivery)
+	if err != nil {
+		return false, utils.WrapError(err, "ApplyWebhook", "while parsing payload")
+	}
+
+	switch event {
+	case "push":
+		return d.applyPush(delivery), nil
+	case "pull_request":
+		pr, ok := delivery["pull_request"].(map[string]interface{})
+		if !ok {
+			return false, utils.WrapError(errors.New("payload has no pull_request"), "ApplyWebhook", event)
+		}
+		d.PRs = upsertPR(d.PRs, pr)
+		return true, nil
+	case "pull_request_review":
+		pr, ok := delivery["pull_request"].(map[string]interface{})
+		if !ok {
+			return false, utils.WrapError(errors.New("payload has no pull_request"), "ApplyWebhook", event)
+		}
+		d.PRs = upsertPR(d.PRs, pr)
+		return true, nil
+	}
+	return false, nil
+}
+
+// applyPush
+// Adds the commits of a push to the default branch, returning false for any other push
+func (d *Dataset) applyPush(delivery map[string]interface{}) bool {
+	repository, _ := delivery["repository"].(map[string]interface{})
+	defaultBranch, _ := repository["default_branch"].(string)
+	ref, _ := delivery["ref"].(string)
+	deleted, _ := delivery["deleted"].(bool)
+	if deleted || defaultBranch == "" || ref != "refs/heads/"+defaultBranch {
+		return false
+	}
+	if forced, _ := delivery["forced"].(bool); forced {
+		// Known commits may no longer be in the history
+		d.NeedsFullRefresh = true
+	}
+
+	knownSHAs := make(map[string]bool)
+	for _, commit := range d.Commits {
+		sha, _ := commit.(map[string]interface{})["sha"].(string)
+		knownSHAs[sha] = true
+	}
+
+	// Commits of a push are oldest first, the dataset keeps the newest first
+	pushed, _ := delivery["commits"].([]interface{})
+	changed := false
+	for _, item := range pushed {
+		pushCommit, ok := item.(map[string]interface{})
+		sha, _ := pushCommit["id"].(string)
+		if !ok || sha == "" || knownSHAs[sha] {
+			continue
+		}
+		knownSHAs[sha] = true
+		commit, changes := d.convertPushCommit(pushCommit, repository)
+		d.Commits = append([]interface{}{commit}, d.Commits...)
+		d.FileHistory = append(changes, d.FileHistory...)
+		d.PendingCommits = append(d.PendingCommits, sha)
+
+		for _, change := range changes {
+			if change.Status == "removed" {
+				delete(d.Files, change.Path)
+				continue
+			}
+			// A blob SHA which matches no tree makes the next Refresh download the file
+			file := d.Files[change.Path]
+			file.SHA = ""
+			d.Files[change.Path] = file
+		}
+		changed = true
+	}
+	return changed
+}
+
+// convertPushCommit
+// Converts a commit of a push payload to the format of GitHub API commits, along with the
+// files it changed, whose line counts are unknown
+func (d *Dataset) convertPushCommit(pushCommit map[string]interface{}, repository map[string]interface{}) (map[string]interface{}, []utils.FileChange) {
+	sha, _ := pushCommit["id"].(string)
+	message, _ := pushCommit["message"].(string)
+	timestamp, _ := pushCommit["timestamp"].(string)
+	author, _ := pushCommit["author"].(map[string]interface{})
+	name, _ := author["name"].(string)
+	email, _ := author["email"].(string)
+	username, _ := author["username"].(string)
+
+	// The API URL of the commit on the instance which sent the delivery, ex:
+	// https://api.github.com/repos/owner/repo/commits{/sha}
+	commitURL, _ := repository["commits_url"].(string)
+	if commitURL == "" {
+		commitURL = DefaultGitHubHosts().API + "/repos/" + d.RepoOwner + "/" + d.RepoName + "/commits{/sha}"
+	}
+	commitURL = strings.Replace(commitURL, "{/sha}", "/"+sha, 1)
+
+	commit := map[string]interface{}{
+		"sha": sha,
+		"url": commitURL,
+		"commit": map[string]interface{}{
+			"message": message,
+			"author":  map[string]interface{}{"name": name, "email": email, "date": timestamp},
+		},
+	}
+	if username != "" {
+		commit["author"] = map[string]interface{}{"login": username}
+	}
+
+	changes := make([]utils.FileChange, 0)
+	for _, status := range []struct{ key, status string }{
+		{"added", "added"}, {"modified", "modified"}, {"removed", "removed"}} {
+		paths, _ := pushCommit[status.key].([]interface{})
+		for _, path := range paths {
+			filePath, _ := path.(string)
+			changes = append(changes, utils.FileChange{SHA: sha, Author: name, Path: filePath,
+				CommitPath: filePath, Status: status.status})
+		}
+	}
+	return commit, changes
+}
+
+// upsertPR
+// Adds a PR, or updates the fields of a known PR with the same number, newest PR first
+func upsertPR(prs []interface{}, pr map[string]interface{}) []interface{} {
+	number, _ := pr["number"].(float64)
+	for _, known := range prs {
+		knownPR := known.(map[string]interface{})
+		if knownNumber, _ := knownPR["number"].(float64); knownNumber == number {
+			// Payload PRs may have fewer fields than GitHub API PRs, so keep the others
+			for key, value := range pr {
+				knownPR[key] = value
+			}
+			return prs
+		}
+	}
+	return mergePRs(prs, []interface{}{pr})
+}
+
+// replaceChanges
+// Replaces the changes of a commit in a file history, keeping the position of the commit
+func replaceChanges(history []utils.FileChange, sha string, changes []utils.FileChange) []utils.FileChange {
+	result := make([]utils.FileChange, 0, len(history)+len(changes))
+	inserted := false
+	for _, change := range history {
+		if change.SHA != sha {
+			result = append(result, change)
+			continue
+		}
+		if !inserted {
+			result = append(result, changes...)
+			inserted = true
+		}
+	}
+	if !inserted {
+		result = append(changes, result...)
+	}
+	return result
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"repo_stats/utils"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"action": "opened"}`)
+	signature := SignWebhook("secret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", body: body, signature: signature, want: true},
+		{name: "wrong secret", secret: "other", body: body, signature: signature},
+		{name: "changed body", secret: "secret", body: []byte(`{"action": "closed"}`), signature: signature},
+		{name: "missing prefix", secret: "secret", body: body, signature: signature[len("sha256="):]},
+		{name: "invalid hex", secret: "secret", body: body, signature: "sha256=not-hex"},
+		{name: "empty", secret: "secret", body: body, signature: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(test.secret, test.body, test.signature); got != test.want {
+				t.Errorf("VerifyWebhookSignature() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// newWebhookDataset
+// Creates a dataset with a known commit, file and PR, as left by a refresh
+func newWebhookDataset() *Dataset {
+	dataset := NewDataset("owner", "repo")
+	dataset.Commits = []interface{}{map[string]interface{}{"sha": "c1"}}
+	dataset.FileHistory = []utils.FileChange{{SHA: "c1", Author: "Ada", Path: "a.go", CommitPath: "a.go",
+		Status: "added", Additions: 3, Changes: 3}}
+	dataset.Files = map[string]DatasetFile{"a.go": {SHA: "blob1", Lines: 3}, "b.go": {SHA: "blob2", Lines: 5}}
+	dataset.PRs = []interface{}{map[string]interface{}{"number": 1.0, "title": "First", "state": "open",
+		"created_at": "2024-01-01T00:00:00Z"}}
+	return dataset
+}
+
+const pushPayload = `{
+	"ref": "refs/heads/main",
+	"forced": %s,
+	"repository": {"default_branch": "main",
+		"commits_url": "https://api.github.com/repos/owner/repo/commits{/sha}"},
+	"commits": [
+		{"id": "c1", "message": "known", "author": {"name": "Ada"}},
+		{"id": "c2", "message": "second", "timestamp": "2024-01-02T00:00:00Z",
+			"author": {"name": "Grace", "email": "grace@example.com", "username": "grace"},
+			"added": ["c.go"], "modified": ["a.go"], "removed": ["b.go"]}
+	]
+}`
+
+func TestApplyWebhookPush(t *testing.T) {
+	dataset := newWebhookDataset()
+	changed, err := dataset.ApplyWebhook("push", []byte(fmt.Sprintf(pushPayload, "false")))
+	if err != nil || !changed {
+		t.Fatalf("ApplyWebhook() = %v, %v, want true, nil", changed, err)
+	}
+
+	if len(dataset.Commits) != 2 || dataset.Commits[0].(map[string]interface{})["sha"] != "c2" {
+		t.Errorf("Commits = %v, want c2 before c1", dataset.Commits)
+	}
+	commit := dataset.Commits[0].(map[string]interface{})
+	if commit["url"] != "https://api.github.com/repos/owner/repo/commits/c2" {
+		t.Errorf("commit url = %v", commit["url"])
+	}
+	if !reflect.DeepEqual(commit["author"], map[string]interface{}{"login": "grace"}) {
+		t.Errorf("commit author = %v, want login grace", commit["author"])
+	}
+	if !reflect.DeepEqual(dataset.PendingCommits, []string{"c2"}) {
+		t.Errorf("PendingCommits = %v, want [c2]", dataset.PendingCommits)
+	}
+	if dataset.NeedsFullRefresh {
+		t.Error("NeedsFullRefresh = true for a push which was not forced")
+	}
+
+	wantHistory := []utils.FileChange{
+		{SHA: "c2", Author: "Grace", Path: "c.go", CommitPath: "c.go", Status: "added"},
+		{SHA: "c2", Author: "Grace", Path: "a.go", CommitPath: "a.go", Status: "modified"},
+		{SHA: "c2", Author: "Grace", Path: "b.go", CommitPath: "b.go", Status: "removed"},
+		{SHA: "c1", Author: "Ada", Path: "a.go", CommitPath: "a.go", Status: "added", Additions: 3, Changes: 3},
+	}
+	if !reflect.DeepEqual(dataset.FileHistory, wantHistory) {
+		t.Errorf("FileHistory = %+v, want %+v", dataset.FileHistory, wantHistory)
+	}
+
+	// Changed files lose their blob SHA so the next refresh downloads them
+	wantFiles := map[string]DatasetFile{"a.go": {Lines: 3}, "c.go": {}}
+	if !reflect.DeepEqual(dataset.Files, wantFiles) {
+		t.Errorf("Files = %+v, want %+v", dataset.Files, wantFiles)
+	}
+
+	// Delivering the same push again changes nothing
+	changed, err = dataset.ApplyWebhook("push", []byte(fmt.Sprintf(pushPayload, "false")))
+	if err != nil || changed || len(dataset.Commits) != 2 {
+		t.Errorf("ApplyWebhook() again = %v, %v with %d commits, want false, nil with 2", changed,
+			err, len(dataset.Commits))
+	}
+}
+
+func TestApplyWebhookForcedPush(t *testing.T) {
+	dataset := newWebhookDataset()
+	_, err := dataset.ApplyWebhook("push", []byte(fmt.Sprintf(pushPayload, "true")))
+	if err != nil {
+		t.Fatalf("ApplyWebhook() error = %v", err)
+	}
+	if !dataset.NeedsFullRefresh {
+		t.Error("NeedsFullRefresh = false after a forced push")
+	}
+}
+
+func TestApplyWebhookIgnored(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+	}{
+		{name: "push to another branch", event: "push",
+			payload: `{"ref": "refs/heads/feature", "repository": {"default_branch": "main"},
+				"commits": [{"id": "c9", "added": ["x.go"]}]}`},
+		{name: "deleted branch", event: "push",
+			payload: `{"ref": "refs/heads/main", "deleted": true, "repository": {"default_branch": "main"}}`},
+		{name: "other event", event: "issues", payload: `{"action": "opened"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataset := newWebhookDataset()
+			changed, err := dataset.ApplyWebhook(test.event, []byte(test.payload))
+			if err != nil || changed {
+				t.Errorf("ApplyWebhook() = %v, %v, want false, nil", changed, err)
+			}
+			if !reflect.DeepEqual(dataset, newWebhookDataset()) {
+				t.Errorf("ApplyWebhook() changed the dataset to %+v", dataset)
+			}
+		})
+	}
+}
+
+func TestApplyWebhookPullRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    []interface{}
+	}{
+		{
+			name:    "updates a known PR, keeping other fields",
+			event:   "pull_request",
+			payload: `{"action": "closed", "pull_request": {"number": 1, "state": "closed"}}`,
+			want: []interface{}{map[string]interface{}{"number": 1.0, "title": "First", "state": "closed",
+				"created_at": "2024-01-01T00:00:00Z"}},
+		},
+		{
+			name:  "adds a new PR first",
+			event: "pull_request",
+			payload: `{"action": "opened", "pull_request": {"number": 2, "title": "Second", "state": "open",
+				"created_at": "2024-02-01T00:00:00Z"}}`,
+			want: []interface{}{
+				map[string]interface{}{"number": 2.0, "title": "Second", "state": "open",
+					"created_at": "2024-02-01T00:00:00Z"},
+				map[string]interface{}{"number": 1.0, "title": "First", "state": "open",
+					"created_at": "2024-01-01T00:00:00Z"},
+			},
+		},
+		{
+			name:    "review updates its PR",
+			event:   "pull_request_review",
+			payload: `{"action": "submitted", "review": {"id": 7}, "pull_request": {"number": 1, "title": "Renamed"}}`,
+			want: []interface{}{map[string]interface{}{"number": 1.0, "title": "Renamed", "state": "open",
+				"created_at": "2024-01-01T00:00:00Z"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataset := newWebhookDataset()
+			changed, err := dataset.ApplyWebhook(test.event, []byte(test.payload))
+			if err != nil || !changed {
+				t.Fatalf("ApplyWebhook() = %v, %v, want true, nil", changed, err)
+			}
+			if !reflect.DeepEqual(dataset.PRs, test.want) {
+				t.Errorf("PRs = %v, want %v", dataset.PRs, test.want)
+			}
+			if len(dataset.PendingCommits) > 0 {
+				t.Errorf("PendingCommits = %v after a PR delivery", dataset.PendingCommits)
+			}
+		})
+	}
+}
+
+func TestApplyWebhookErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+	}{
+		{name: "invalid JSON", event: "push", payload: `{`},
+		{name: "pull_request without a PR", event: "pull_request", payload: `{"action": "opened"}`},
+		{name: "review without a PR", event: "pull_request_review", payload: `{"review": {"id": 7}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed, err := newWebhookDataset().ApplyWebhook(test.event, []byte(test.payload))
+			if err == nil || changed {
+				t.Errorf("ApplyWebhook() = %v, %v, want false and an error", changed, err)
+			}
+		})
+	}
+}
+
+// fakeProvider
+// A Provider serving the history of a fakeSource
+type fakeProvider struct {
+	*fakeSource
+}
+
+func (f fakeProvider) Refresh(dataset *Dataset) error {
+	return refreshDataset(f.fakeSource, dataset)
+}
+
+func (f fakeProvider) FileURL(fileName string) string {
+	return "https://example.com/" + fileName
+}
+
+func (f fakeProvider) GetRateLimitRemainingString() string {
+	return "no limit"
+}
+
+func TestRefreshPending(t *testing.T) {
+	dataset := newWebhookDataset()
+	_, err := dataset.ApplyWebhook("push", []byte(fmt.Sprintf(pushPayload, "false")))
+	if err != nil {
+		t.Fatalf("ApplyWebhook() error = %v", err)
+	}
+
+	source := &fakeSource{}
+	err = dataset.RefreshPending(fakeProvider{source})
+	if err != nil {
+		t.Fatalf("RefreshPending() error = %v", err)
+	}
+	if !reflect.DeepEqual(source.fetched, []string{"c2"}) {
+		t.Errorf("fetched changes of %v, want [c2]", source.fetched)
+	}
+	if len(dataset.PendingCommits) > 0 {
+		t.Errorf("PendingCommits = %v, want none", dataset.PendingCommits)
+	}
+	wantHistory := []utils.FileChange{
+		{SHA: "c2", Author: "Ada", Path: "c2.go", CommitPath: "c2.go", Status: "added", Additions: 1, Changes: 1},
+		{SHA: "c1", Author: "Ada", Path: "a.go", CommitPath: "a.go", Status: "added", Additions: 3, Changes: 3},
+	}
+	if !reflect.DeepEqual(dataset.FileHistory, wantHistory) {
+		t.Errorf("FileHistory = %+v, want %+v", dataset.FileHistory, wantHistory)
+	}
+	if len(dataset.Commits) != 2 || dataset.Files["a.go"].SHA != "" {
+		t.Errorf("RefreshPending() changed commits or files: %v, %+v", dataset.Commits, dataset.Files)
+	}
+}
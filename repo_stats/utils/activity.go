@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ActivityPeriod
+// The number of commits and PRs created during a single month
+type ActivityPeriod struct {
+	// The month, formatted as YYYY-MM
+	Period string `json:"period"`
+	// The number of commits authored during the month
+	Commits int `json:"commits"`
+	// The number of PRs opened during the month
+	PRs int `json:"prs"`
+}
+
+// languageNames
+// A map of file extension to the name of its language
+var languageNames = map[string]string{
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript",
+	".py": "Python", ".go": "Go", ".java": "Java", ".kt": "Kotlin", ".swift": "Swift",
+	".rb": "Ruby", ".php": "PHP", ".rs": "Rust", ".c": "C", ".h": "C", ".cpp": "C++", ".hpp": "C++",
+	".cs": "C#", ".dart": "Dart", ".vue": "Vue", ".svelte": "Svelte",
+	".html": "HTML", ".css": "CSS", ".scss": "SCSS", ".sass": "SCSS", ".less": "Less",
+	".sql": "SQL", ".sh": "Shell", ".bash": "Shell", ".yaml": "YAML", ".yml": "YAML",
+	".toml": "TOML", ".xml": "XML", ".graphql": "GraphQL", ".prisma": "Prisma",
+}
+
+// Activity
+// Counts commits by author date and PRs by creation date per month, including
+// months without any activity between the first and last active month
+//
+// Returns array of activity periods, oldest first
+func (x *Stats) Activity() []ActivityPeriod {
+	commits := make(map[string]int)
+	for _, commit := range x.allCommits {
+		_commit, _ := commit.(map[string]interface{})["commit"].(map[string]interface{})
+		_author, _ := _commit["author"].(map[string]interface{})
+		_date, _ := _author["date"].(string)
+		if period, ok := activityPeriod(_date); ok {
+			commits[period]++
+		}
+	}
+
+	prs := make(map[string]int)
+	for _, PR := range x.allPRs {
+		_created, _ := PR.(map[string]interface{})["created_at"].(string)
+		if period, ok := activityPeriod(_created); ok {
+			prs[period]++
+		}
+	}
+
+	periods := make([]string, 0, len(commits)+len(prs))
+	for period := range commits {
+		periods = append(periods, period)
+	}
+	for period := range prs {
+		periods = append(periods, period)
+	}
+	result := make([]ActivityPeriod, 0)
+	if len(periods) == 0 {
+		return result
+	}
+	sort.Strings(periods)
+
+	first, _ := time.Parse("2006-01", periods[0])
+	last, _ := time.Parse("2006-01", periods[len(periods)-1])
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		period := month.Format("2006-01")
+		result = append(result, ActivityPeriod{Period: period, Commits: commits[period], PRs: prs[period]})
+	}
+	return result
+}
+
+// Languages
+// Sums the lines of every valid file per language, based on the file extension
+//
+// Returns array of languages ranked by lines
+func (x *Stats) Languages() []RankedItem {
+	languages := make(map[string]int)
+	for file, lines := range x.filterFiles(x.fileSizes) {
+		languages[languageName(file)] += lines
+	}
+	return rankedItems(languages, 0)
+}
+
+// activityPeriod
+// Gets the month of a timestamp returned from GitHub API, formatted as YYYY-MM
+//
+// Returns the month and true, or false if the timestamp could not be parsed
+func activityPeriod(timestamp string) (string, bool) {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return "", false
+	}
+	return parsed.UTC().Format("2006-01"), true
+}
+
+// languageName
+// Gets the language of a file from its extension, "Other" if unknown
+func languageName(file string) string {
+	if name, ok := languageNames[strings.ToLower(path.Ext(file))]; ok {
+		return name
+	}
+	return "Other"
+}
@@ -0,0 +1,429 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// browserSection
+// A scrollable table of the browser, switched between with the keyboard
+type browserSection struct {
+	title   string
+	columns []string
+	rows    []browserRow
+}
+
+// browserRow
+// A single row of a browser section
+type browserRow struct {
+	// Cells in column order, the first cell is matched by search
+	cells []string
+	// Gets the lines shown when drilling into the row
+	details func() []string
+}
+
+// browser
+// State of the full screen results browser
+type browser struct {
+	sections []browserSection
+	section  int
+	cursor   int
+	offset   int
+	// Only rows whose first cell contains filter are shown
+	filter    string
+	searching bool
+	// The title and lines of the row being drilled into, nil when showing a section
+	detailsTitle  string
+	details       []string
+	detailsOffset int
+	width         int
+	height        int
+}
+
+// Browse
+// Opens a full screen browser of every collected statistic, which lets the user switch
+// sections, scroll full leaderboards, search file paths and drill into contributors and files
+//
+// Returns an error if stdin or stdout is not a terminal
+func (x *Stats) Browse() error {
+	stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+		return WrapError(errors.New("not a terminal"), "Browse", "the browser needs an interactive terminal")
+	}
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return WrapError(err, "Browse", "while entering raw mode")
+	}
+	// Use the alternate screen so the previous output is restored on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		term.Restore(stdin, state)
+	}()
+
+	b := &browser{sections: x.browserSections()}
+	input := make([]byte, 16)
+	for {
+		b.width, b.height, err = term.GetSize(stdout)
+		if err != nil {
+			b.width, b.height = 80, 24
+		}
+		b.draw()
+
+		n, err := os.Stdin.Read(input)
+		if err != nil {
+			return WrapError(err, "Browse", "while reading input")
+		}
+		if !b.handleKey(string(input[:n])) {
+			return nil
+		}
+	}
+}
+
+// browserSections
+// Builds the sections of the browser from the collected statistics
+func (x *Stats) browserSections() []browserSection {
+	contributors := browserSection{title: "Contributors", columns: []string{"Contributor", "PRs", "Commits", "Changes"}}
+	authorChanges := make(map[string]int)
+	for _, change := range x.fileHistory {
+		authorChanges[change.Author] += change.Changes
+	}
+	for _, row := range x.contributorRows()[1:] {
+		name := row[0]
+		contributors.rows = append(contributors.rows, browserRow{
+			cells:   []string{name, row[1], row[2], strconv.Itoa(authorChanges[name])},
+			details: func() []string { return x.contributorDetails(name) },
+		})
+	}
+
+	files := browserSection{title: "Files", columns: []string{"File", "Lines", "Changes", "useState"}}
+	fileRows := x.fileRows()[1:]
+	sort.SliceStable(fileRows, func(i, j int) bool {
+		left, _ := strconv.Atoi(fileRows[i][2])
+		right, _ := strconv.Atoi(fileRows[j][2])
+		return left > right
+	})
+	for _, row := range fileRows {
+		file := row[0]
+		files.rows = append(files.rows, browserRow{
+			cells:   []string{file, row[2], row[3], row[4]},
+			details: func() []string { return x.fileDetails(file) },
+		})
+	}
+
+	hotspots := browserSection{title: "Hotspots", columns: []string{"File", "Score", "Lines", "Changes", "Authors"}}
+	for _, hotspot := range x.Hotspots() {
+		file := hotspot.Path
+		hotspots.rows = append(hotspots.rows, browserRow{
+			cells: []string{file, fmt.Sprintf("%.2f", hotspot.Score), strconv.Itoa(hotspot.Lines),
+				strconv.Itoa(hotspot.Changes), strconv.Itoa(hotspot.Authors)},
+			details: func() []string { return x.fileDetails(file) },
+		})
+	}
+
+	ownership := browserSection{title: "Ownership", columns: []string{"Directory", "Bus Factor", "Top Share", "Changes"}}
+	for _, dir := range x.DirectoryOwnership() {
+		owners := dir.Owners
+		ownership.rows = append(ownership.rows, browserRow{
+			cells: []string{dir.Path, strconv.Itoa(dir.BusFactor),
+				fmt.Sprintf("%.0f%%", owners[0].Share*100), strconv.Itoa(dir.Changes)},
+			details: func() []string { return ownerLines(owners) },
+		})
+	}
+
+	graveyard := browserSection{title: "Graveyard", columns: []string{"Deleted File", "Changes"}}
+	for _, item := range x.Graveyard(0).Files {
+		file := item.Name
+		graveyard.rows = append(graveyard.rows, browserRow{
+			cells:   []string{file, strconv.Itoa(item.Value)},
+			details: func() []string { return x.fileDetails(file) },
+		})
+	}
+
+	return []browserSection{contributors, files, hotspots, ownership, graveyard}
+}
+
+// contributorDetails
+// Gets the lines shown when drilling into a contributor
+func (x *Stats) contributorDetails(name string) []string {
+	fileChanges := make(map[string]int)
+	total := 0
+	for _, change := range x.fileHistory {
+		if change.Author == name {
+			fileChanges[change.Path] += change.Changes
+			total += change.Changes
+		}
+	}
+
+	lines := []string{
+		"PRs:           " + strconv.Itoa(x.prAttribution[name]),
+		"Commits:       " + strconv.Itoa(x.commitAttribution[name]),
+		"Line changes:  " + strconv.Itoa(total),
+		"Files touched: " + strconv.Itoa(len(fileChanges)),
+		"",
+		"Most changed files:",
+	}
+	for _, item := range rankedItems(fileChanges, 0) {
+		lines = append(lines, fmt.Sprintf("  %8d  %s", item.Value, item.Name))
+	}
+	return lines
+}
+
+// fileDetails
+// Gets the lines shown when drilling into a file
+func (x *Stats) fileDetails(file string) []string {
+	authorChanges := make(map[string]int)
+	for _, change := range x.fileHistory {
+		if change.Path == file {
+			authorChanges[change.Author] += change.Changes
+		}
+	}
+
+	lines := []string{
+		"Exists:         " + strconv.FormatBool(x.isExistingFile(file)),
+		"Lines:          " + strconv.Itoa(x.fileSizes[file]),
+		"Changes:        " + strconv.Itoa(x.fileChanges[file]),
+		"useState calls: " + strconv.Itoa(x.allUseStates[file]),
+	}
+	for _, rename := range x.RenameHistories() {
+		if rename.Path == file {
+			lines = append(lines, "Previously:     "+strings.Join(rename.PreviousPaths, " <- "))
+		}
+	}
+
+	for _, ownership := range x.FileOwnership() {
+		if ownership.Path == file {
+			lines = append(lines, "Bus factor:     "+strconv.Itoa(ownership.BusFactor), "", "Authors:")
+			lines = append(lines, ownerLines(ownership.Owners)...)
+		}
+	}
+	return lines
+}
+
+// ownerLines
+// Formats owner shares as one line each
+func ownerLines(owners []OwnerShare) []string {
+	lines := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		lines = append(lines, fmt.Sprintf("  %5.1f%%  %8d  %s", owner.Share*100, owner.Changes, owner.Author))
+	}
+	return lines
+}
+
+// visibleRows
+// Gets the rows of the current section which match the filter
+func (b *browser) visibleRows() []browserRow {
+	rows := b.sections[b.section].rows
+	if b.filter == "" {
+		return rows
+	}
+	result := make([]browserRow, 0)
+	for _, row := range rows {
+		if strings.Contains(strings.ToLower(row.cells[0]), strings.ToLower(b.filter)) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// pageSize
+// Gets the number of rows which fit on screen between the header and the status line
+func (b *browser) pageSize() int {
+	return max(1, b.height-5)
+}
+
+// handleKey
+// Updates the browser state for a single key press
+//
+// Returns false if the browser should close
+func (b *browser) handleKey(key string) bool {
+	if b.searching {
+		switch key {
+		case "\r", "\n":
+			b.searching = false
+		case "\x1b":
+			b.searching = false
+			b.filter = ""
+		case "\x7f", "\b":
+			if len(b.filter) > 0 {
+				b.filter = b.filter[:len(b.filter)-1]
+			}
+		default:
+			if len(key) == 1 && key[0] >= ' ' && key[0] <= '~' {
+				b.filter += key
+			}
+		}
+		b.cursor, b.offset = 0, 0
+		return true
+	}
+
+	if b.details != nil {
+		switch key {
+		case "q", "\x1b", "\x7f", "\b", "\r", "\x1b[D", "h":
+			b.details = nil
+		case "\x1b[A", "k":
+			b.detailsOffset = max(0, b.detailsOffset-1)
+		case "\x1b[B", "j":
+			b.detailsOffset = min(max(0, len(b.details)-b.pageSize()), b.detailsOffset+1)
+		case "\x03":
+			return false
+		}
+		return true
+	}
+
+	rows := b.visibleRows()
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\t", "\x1b[C", "l":
+		b.switchSection(1)
+	case "\x1b[Z", "\x1b[D", "h":
+		b.switchSection(-1)
+	case "\x1b[A", "k":
+		b.cursor--
+	case "\x1b[B", "j":
+		b.cursor++
+	case "\x1b[5~":
+		b.cursor -= b.pageSize()
+	case "\x1b[6~", " ":
+		b.cursor += b.pageSize()
+	case "g", "\x1b[H":
+		b.cursor = 0
+	case "G", "\x1b[F":
+		b.cursor = len(rows) - 1
+	case "/":
+		b.searching = true
+		b.filter = ""
+	case "\x1b":
+		b.filter = ""
+	case "\r", "\n":
+		if b.cursor < len(rows) && rows[b.cursor].details != nil {
+			b.detailsTitle = rows[b.cursor].cells[0]
+			b.details = rows[b.cursor].details()
+			b.detailsOffset = 0
+		}
+	default:
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' && int(key[0]-'1') < len(b.sections) {
+			b.switchSection(int(key[0]-'1') - b.section)
+		}
+	}
+
+	b.cursor = max(0, min(b.cursor, len(rows)-1))
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+b.pageSize() {
+		b.offset = b.cursor - b.pageSize() + 1
+	}
+	return true
+}
+
+// switchSection
+// Moves to another section by delta, wrapping around, and clears the cursor and filter
+func (b *browser) switchSection(delta int) {
+	b.section = (b.section + delta + len(b.sections)) % len(b.sections)
+	b.cursor, b.offset, b.filter = 0, 0, ""
+}
+
+// draw
+// Redraws the whole screen
+func (b *browser) draw() {
+	var screen strings.Builder
+	screen.WriteString("\033[H\033[2J")
+
+	// Section tabs
+	for index, section := range b.sections {
+		label := fmt.Sprintf(" %d %s ", index+1, section.title)
+		if index == b.section {
+			screen.WriteString(Title.String() + "[" + label + "]" + End.String())
+		} else {
+			screen.WriteString(Subtle.String() + " " + label + " " + End.String())
+		}
+	}
+	screen.WriteString("\r\n\r\n")
+
+	var status string
+	if b.details != nil {
+		screen.WriteString(Title.String() + fit(b.detailsTitle, b.width) + End.String() + "\r\n")
+		end := min(len(b.details), b.detailsOffset+b.pageSize())
+		for _, line := range b.details[b.detailsOffset:end] {
+			screen.WriteString(fit(line, b.width) + "\r\n")
+		}
+		status = "up/down scroll  esc back  ctrl+c quit"
+	} else {
+		section := b.sections[b.section]
+		widths := b.columnWidths(section)
+		screen.WriteString(TitleNoBold.String() + formatCells(section.columns, widths) + End.String() + "\r\n")
+
+		rows := b.visibleRows()
+		end := min(len(rows), b.offset+b.pageSize())
+		for index := b.offset; index < end; index++ {
+			line := formatCells(rows[index].cells, widths)
+			if index == b.cursor {
+				// Reset with a raw code, End is empty when colors are disabled
+				screen.WriteString("\033[7m" + line + "\033[0m\r\n")
+			} else {
+				screen.WriteString(line + "\r\n")
+			}
+		}
+		if len(rows) == 0 {
+			screen.WriteString(Subtle.String() + "Nothing to show" + End.String() + "\r\n")
+		}
+		status = fmt.Sprintf("%d/%d  tab/1-%d section  up/down/pgup/pgdn scroll  enter details  / search  q quit",
+			min(b.cursor+1, len(rows)), len(rows), len(b.sections))
+	}
+
+	// Status line on the last row
+	if b.searching {
+		status = "search: " + b.filter + "_"
+	} else if b.filter != "" && b.details == nil {
+		status = "filter: " + b.filter + " (esc to clear)  " + status
+	}
+	screen.WriteString(fmt.Sprintf("\033[%d;1H%s%s%s", b.height, Subtle, fit(status, b.width), End))
+	fmt.Print(screen.String())
+}
+
+// columnWidths
+// Gives every column after the first a fixed width, and the first column the rest of the screen
+func (b *browser) columnWidths(section browserSection) []int {
+	const numberWidth = 12
+	widths := make([]int, len(section.columns))
+	widths[0] = max(10, b.width-numberWidth*(len(section.columns)-1)-1)
+	for index := 1; index < len(widths); index++ {
+		widths[index] = numberWidth
+	}
+	return widths
+}
+
+// formatCells
+// Pads the first cell on the right and every other cell on the left to their column widths
+func formatCells(cells []string, widths []int) string {
+	var line strings.Builder
+	for index, cell := range cells {
+		cell = fit(cell, widths[index])
+		padding := strings.Repeat(" ", widths[index]-len([]rune(cell)))
+		if index == 0 {
+			line.WriteString(cell + padding)
+		} else {
+			line.WriteString(padding + cell)
+		}
+	}
+	return line.String()
+}
+
+// fit
+// Shortens text to at most width characters, keeping the end which is the most specific
+// part of a file path
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return truncate(text, width)
+}
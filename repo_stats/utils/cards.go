@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Card dimensions, a portrait "story" for social posts
+const (
+	cardWidth  = 1080
+	cardHeight = 1920
+	cardMargin = 90
+)
+
+// Card
+// A single highlight of a report, rendered as a fixed size image
+type Card struct {
+	// Identifies the highlight, used in file names
+	Key string
+	// What the highlight is, such as "Top Committer"
+	Label string
+	// The highlight itself, such as a login or a number
+	Value string
+	// Additional context for the value, such as "312 commits"
+	Detail string
+}
+
+// CardTheme
+// The colors used to render cards
+type CardTheme struct {
+	Background color.RGBA
+	Title      color.RGBA
+	Text       color.RGBA
+	Accent     color.RGBA
+	Subtle     color.RGBA
+}
+
+// DefaultCardTheme
+// Returns a CardTheme using the terminal Color palette on a dark background
+func DefaultCardTheme() CardTheme {
+	return CardTheme{
+		Background: color.RGBA{R: 17, G: 19, B: 24, A: 255},
+		Title:      colorRGBA(TitleNoBold),
+		Text:       colorRGBA(Success),
+		Accent:     colorRGBA(Highlight),
+		Subtle:     colorRGBA(Subtle),
+	}
+}
+
+// ParseCardTheme
+// Overrides colors of the default card theme
+//
+// Parameters:
+//   - spec: comma separated role=#rrggbb pairs, roles are background, title, text, accent
+//     and subtle, ex: "background=#000000,accent=#ff8800"
+//
+// Returns the resulting CardTheme
+func ParseCardTheme(spec string) (CardTheme, error) {
+	theme := DefaultCardTheme()
+	if strings.TrimSpace(spec) == "" {
+		return theme, nil
+	}
+
+	roles := map[string]*color.RGBA{"background": &theme.Background, "title": &theme.Title,
+		"text": &theme.Text, "accent": &theme.Accent, "subtle": &theme.Subtle}
+	for _, pair := range strings.Split(spec, ",") {
+		items := strings.SplitN(pair, "=", 2)
+		if len(items) != 2 {
+			return theme, WrapError(errors.New("expected role=#rrggbb"), "ParseCardTheme", pair)
+		}
+		role, ok := roles[strings.TrimSpace(strings.ToLower(items[0]))]
+		if !ok {
+			return theme, WrapError(errors.New("unknown role"), "ParseCardTheme", items[0])
+		}
+		parsed, err := parseHexColor(strings.TrimSpace(items[1]))
+		if err != nil {
+			return theme, WrapError(err, "ParseCardTheme", pair)
+		}
+		*role = parsed
+	}
+	return theme, nil
+}
+
+// Cards
+// Gets a card for every highlight of the report, skipping highlights without data
+//
+// Returns array of cards in display order
+func (r *Report) Cards() []Card {
+	cards := make([]Card, 0)
+	for _, total := range r.Totals {
+		cards = append(cards, Card{Key: strings.ReplaceAll(total.Key, "_", "-"), Label: total.Label,
+			Value: strconv.Itoa(total.Value)})
+	}
+
+	highlights := []struct {
+		ranking string
+		key     string
+		label   string
+		unit    string
+	}{
+		{"commits", "top-committer", "Top Committer", "commits"},
+		{"prs", "top-pr-author", "Top PR Author", "PRs"},
+		{"file_sizes", "biggest-file", "Biggest File", "lines"},
+		{"file_changes", "most-changed-file", "Most Changed File", "line changes"},
+		{"use_states", "top-use-state-file", "Top useState File", "useState calls"},
+		{"messages", "top-poster", "Top Poster", "messages"},
+		{"mentions_received", "most-mentioned", "Most Mentioned", "mentions"},
+		{"reactions_received", "most-reacted", "Most Reacted To", "reactions"},
+		{"thread_replies", "top-thread-replier", "Top Thread Replier", "replies"},
+		{"busiest_days", "busiest-day", "Busiest Day", "messages"},
+		{"channels", "most-active-channel", "Most Active Channel", "messages"},
+	}
+	for _, highlight := range highlights {
+		for _, ranking := range r.Rankings {
+			if ranking.Key != highlight.ranking || len(ranking.Items) == 0 {
+				continue
+			}
+			top := ranking.Items[0]
+			cards = append(cards, Card{Key: highlight.key, Label: highlight.label, Value: top.Name,
+				Detail: strconv.Itoa(top.Value) + " " + highlight.unit})
+		}
+	}
+
+	if len(r.Hotspots) > 0 {
+		hotspot := r.Hotspots[0]
+		cards = append(cards, Card{Key: "top-hotspot", Label: "Top Hotspot", Value: hotspot.Path,
+			Detail: fmt.Sprintf("%d lines, %d changes", hotspot.Lines, hotspot.Changes)})
+	}
+	return cards
+}
+
+// WriteCards
+// Writes every card of the report as both SVG and PNG files, named by position and key
+//
+// Parameters:
+//   - dir: directory to write the cards to, created if missing
+//   - theme: colors used to render the cards
+//
+// Returns any errors
+func (r *Report) WriteCards(dir string, theme CardTheme) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return WrapError(err, "WriteCards", "while creating "+dir)
+	}
+
+	for index, card := range r.Cards() {
+		name := filepath.Join(dir, fmt.Sprintf("%02d-%s", index+1, card.Key))
+		err = writeCardFile(name+".svg", func(w io.Writer) error { return card.WriteSVG(w, r.Repo, theme) })
+		if err != nil {
+			return err
+		}
+		err = writeCardFile(name+".png", func(w io.Writer) error { return card.WritePNG(w, r.Repo, theme) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSVG
+// Writes the card as an SVG image
+//
+// Parameters:
+//   - w: writer to output the image to
+//   - repo: the repository the card is for, as owner/name
+//   - theme: colors used to render the card
+//
+// Returns any errors
+func (c Card) WriteSVG(w io.Writer, repo string, theme CardTheme) error {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		cardWidth, cardHeight, cardWidth, cardHeight)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, cardWidth, cardHeight, hexColor(theme.Background))
+	fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+		(cardWidth-160)/2, 300, 160, 16, hexColor(theme.Accent))
+	for _, line := range c.layout(repo, theme) {
+		fontSize := line.scale * 10
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" fill="%s" font-family="monospace" font-size="%d" text-anchor="middle">%s</text>`,
+			cardWidth/2, line.y+line.scale*cardFontHeight, hexColor(line.color), fontSize,
+			template.HTMLEscapeString(line.text))
+	}
+	svg.WriteString(`</svg>`)
+	_, err := io.WriteString(w, svg.String())
+	return err
+}
+
+// WritePNG
+// Writes the card as a PNG image, drawing text with the built in bitmap font
+//
+// Parameters:
+//   - w: writer to output the image to
+//   - repo: the repository the card is for, as owner/name
+//   - theme: colors used to render the card
+//
+// Returns any errors
+func (c Card) WritePNG(w io.Writer, repo string, theme CardTheme) error {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: theme.Background}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect((cardWidth-160)/2, 300, (cardWidth+160)/2, 316),
+		&image.Uniform{C: theme.Accent}, image.Point{}, draw.Src)
+	for _, line := range c.layout(repo, theme) {
+		drawText(img, line.text, line.y, line.scale, line.color)
+	}
+	return png.Encode(w, img)
+}
+
+// cardLine
+// A single line of centered text on a card
+type cardLine struct {
+	text  string
+	y     int
+	scale int
+	color color.RGBA
+}
+
+// layout
+// Positions the text of the card, shrinking long values until they fit the card width
+func (c Card) layout(repo string, theme CardTheme) []cardLine {
+	lines := []cardLine{
+		{text: "WRAPPED", y: 200, scale: 8, color: theme.Title},
+		{text: c.Label, y: 700, scale: 10, color: theme.Title},
+	}
+
+	// Long values, such as file paths, wrap onto at most 3 lines
+	valueScale := 16
+	valueLines := wrapCardText(c.Value, valueScale)
+	for len(valueLines) > 3 && valueScale > 6 {
+		valueScale -= 2
+		valueLines = wrapCardText(c.Value, valueScale)
+	}
+	if len(valueLines) > 3 {
+		// Keep the end of the value, which is the most specific part of a file path
+		valueLines = valueLines[len(valueLines)-3:]
+		valueLines[0] = "..." + string([]rune(valueLines[0])[3:])
+	}
+	y := 900
+	for _, text := range valueLines {
+		lines = append(lines, cardLine{text: text, y: y, scale: valueScale, color: theme.Text})
+		y += valueScale * (cardFontHeight + 4)
+	}
+
+	lines = append(lines,
+		cardLine{text: c.Detail, y: y + 80, scale: 7, color: theme.Accent},
+		cardLine{text: repo, y: cardHeight - 200, scale: 5, color: theme.Subtle})
+	return lines
+}
+
+// cardChars
+// Gets the number of characters which fit on a card line at a text scale
+func cardChars(scale int) int {
+	return (cardWidth - 2*cardMargin) / ((cardFontWidth + 1) * scale)
+}
+
+// wrapCardText
+// Splits text into lines which fit on a card at a text scale, preferring to break after "/"
+func wrapCardText(text string, scale int) []string {
+	width := cardChars(scale)
+	lines := make([]string, 0)
+	runes := []rune(text)
+	for len(runes) > width {
+		split := width
+		for index := width; index > width/2; index-- {
+			if runes[index-1] == '/' || runes[index-1] == ' ' {
+				split = index
+				break
+			}
+		}
+		lines = append(lines, string(runes[:split]))
+		runes = runes[split:]
+	}
+	return append(lines, string(runes))
+}
+
+// drawText
+// Draws a single line of text centered horizontally using the built in bitmap font
+//
+// Parameters:
+//   - img: image to draw onto
+//   - text: text to draw, characters outside printable ASCII are drawn as "?"
+//   - y: top of the line in pixels
+//   - scale: size of a single font pixel in image pixels
+//   - textColor: color of the text
+func drawText(img *image.RGBA, text string, y int, scale int, textColor color.RGBA) {
+	runes := []rune(text)
+	advance := (cardFontWidth + 1) * scale
+	x := (cardWidth - len(runes)*advance + scale) / 2
+	fill := &image.Uniform{C: textColor}
+	for _, char := range runes {
+		if char < ' ' || char > '~' {
+			char = '?'
+		}
+		glyph := cardFont[char-' ']
+		for row := 0; row < cardFontHeight; row++ {
+			for column := 0; column < cardFontWidth; column++ {
+				if glyph[row]&(1<<(cardFontWidth-1-column)) == 0 {
+					continue
+				}
+				pixel := image.Rect(x+column*scale, y+row*scale, x+(column+1)*scale, y+(row+1)*scale)
+				draw.Draw(img, pixel, fill, image.Point{}, draw.Src)
+			}
+		}
+		x += advance
+	}
+}
+
+// writeCardFile
+// Creates a file and writes to it with write
+func writeCardFile(name string, write func(w io.Writer) error) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return WrapError(err, "writeCardFile", "while creating "+name)
+	}
+	defer file.Close()
+	err = write(file)
+	if err != nil {
+		return WrapError(err, "writeCardFile", "while writing "+name)
+	}
+	return nil
+}
+
+// colorRGBA
+// Converts a truecolor Color to an opaque color.RGBA, white if it has no truecolor component
+func colorRGBA(c Color) color.RGBA {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
+
+// parseHexColor
+// Parses a color in the form #rrggbb
+func parseHexColor(hex string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(hex) != 7 || hex[0] != '#' {
+		return color.RGBA{}, errors.New("expected color in the form #rrggbb")
+	}
+	_, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}, nil
+}
+
+// hexColor
+// Formats a color in the form #rrggbb
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ColorMode
+// How colors are written to the terminal
+type ColorMode int
+
+const (
+	// ColorNone writes no escape codes at all
+	ColorNone ColorMode = iota
+	// Color16 writes the 16 basic ANSI colors
+	Color16
+	// Color256 writes the xterm 256 color palette
+	Color256
+	// ColorTrue writes 24-bit truecolor
+	ColorTrue
+)
+
+// colorMode
+// The ColorMode used by every Color, set with SetColorMode
+var colorMode = ColorTrue
+
+// Color
+// A terminal color, either an RGB foreground color or a raw escape code such as End
+type Color struct {
+	r, g, b uint8
+	// True if r, g and b are set
+	rgb  bool
+	bold bool
+	// A raw escape code, used when rgb is false
+	code string
+}
+
+func (c Color) String() string {
+	if colorMode == ColorNone {
+		return ""
+	}
+	if !c.rgb {
+		return c.code
+	}
+
+	prefix := ""
+	if c.bold {
+		prefix = "\033[1m"
+	}
+	switch colorMode {
+	case Color16:
+		return prefix + fmt.Sprintf("\033[%dm", ansi16(c.r, c.g, c.b))
+	case Color256:
+		return prefix + fmt.Sprintf("\033[38;5;%dm", ansi256(c.r, c.g, c.b))
+	default:
+		return prefix + fmt.Sprintf("\033[38;2;%d;%d;%dm", c.r, c.g, c.b)
+	}
+}
+
+// RGB
+// Gets the red, green and blue components of a Color
+//
+// Returns the components and true, or false if the Color has no RGB components (None, End)
+func (c Color) RGB() (uint8, uint8, uint8, bool) {
+	return c.r, c.g, c.b, c.rgb
+}
+
+// rgbColor
+// Creates a Color from red, green and blue components
+func rgbColor(r uint8, g uint8, b uint8, bold bool) Color {
+	return Color{r: r, g: g, b: b, rgb: true, bold: bold}
+}
+
+// Predefined color constants, the roles can be changed with ApplyTheme
+var (
+	Err         = rgbColor(205, 41, 73, false)
+	Success     = rgbColor(127, 255, 212, false)
+	Subtle      = rgbColor(105, 105, 105, false)
+	Title       = rgbColor(0, 255, 255, true)
+	TitleNoBold = rgbColor(0, 255, 255, false)
+	Highlight   = rgbColor(153, 102, 204, false)
+	Bold        = Color{code: "\033[1m"}
+	End         = Color{code: "\033[0m"}
+	None        = Color{code: ""}
+)
+
+// Theme
+// The colors of each output role, as #rrggbb
+type Theme struct {
+	Err       string `json:"err"`
+	Success   string `json:"success"`
+	Subtle    string `json:"subtle"`
+	Title     string `json:"title"`
+	Highlight string `json:"highlight"`
+}
+
+// Themes
+// The built in themes, by name
+var Themes = map[string]Theme{
+	"default": {Err: "#cd2949", Success: "#7fffd4", Subtle: "#696969", Title: "#00ffff", Highlight: "#9966cc"},
+	// Darker colors which stay readable on light backgrounds
+	"light": {Err: "#b00020", Success: "#00796b", Subtle: "#757575", Title: "#005f87", Highlight: "#6a1b9a"},
+	"solarized": {Err: "#dc322f", Success: "#859900", Subtle: "#586e75", Title: "#268bd2",
+		Highlight: "#d33682"},
+	"high-contrast": {Err: "#ff0000", Success: "#00ff00", Subtle: "#c0c0c0", Title: "#ffff00",
+		Highlight: "#ff00ff"},
+}
+
+// SetColorMode
+// Sets how every Color is written
+func SetColorMode(mode ColorMode) {
+	colorMode = mode
+}
+
+// ParseColorMode
+// Parses a color mode name: "auto", "truecolor", "256", "16" or "none"
+//
+// Returns the mode, or auto as true if the mode should be detected
+func ParseColorMode(name string) (ColorMode, bool, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return ColorTrue, true, nil
+	case "truecolor", "24bit":
+		return ColorTrue, false, nil
+	case "256":
+		return Color256, false, nil
+	case "16":
+		return Color16, false, nil
+	case "none", "never":
+		return ColorNone, false, nil
+	}
+	return ColorNone, false, WrapError(errors.New("unknown color mode"), "ParseColorMode", name)
+}
+
+// DetectColorMode
+// Detects the best ColorMode for the writer set with SetOutput. Colors are disabled if
+// NO_COLOR is set or the writer is not a terminal, otherwise the mode follows COLORTERM and TERM
+//
+// Returns the detected ColorMode
+func DetectColorMode() ColorMode {
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorNone
+	}
+	file, ok := output.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return ColorNone
+	}
+
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	termName := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case termName == "dumb":
+		return ColorNone
+	case colorTerm == "truecolor" || colorTerm == "24bit" || os.Getenv("WT_SESSION") != "":
+		return ColorTrue
+	case strings.Contains(termName, "256color"):
+		return Color256
+	}
+	return Color16
+}
+
+// ApplyTheme
+// Sets the Err, Success, Subtle, Title, TitleNoBold and Highlight colors from a theme,
+// roles left empty keep their current color
+//
+// Parameters:
+//   - theme: the theme to apply
+//
+// Returns an error if any color is not in the form #rrggbb
+func ApplyTheme(theme Theme) error {
+	roles := []struct {
+		hex    string
+		colors []*Color
+	}{
+		{theme.Err, []*Color{&Err}},
+		{theme.Success, []*Color{&Success}},
+		{theme.Subtle, []*Color{&Subtle}},
+		{theme.Title, []*Color{&Title, &TitleNoBold}},
+		{theme.Highlight, []*Color{&Highlight}},
+	}
+	for _, role := range roles {
+		if role.hex == "" {
+			continue
+		}
+		parsed, err := parseHexColor(role.hex)
+		if err != nil {
+			return WrapError(err, "ApplyTheme", role.hex)
+		}
+		for _, c := range role.colors {
+			*c = rgbColor(parsed.R, parsed.G, parsed.B, c.bold)
+		}
+	}
+	return nil
+}
+
+// LoadTheme
+// Gets a built in theme by name, or reads a JSON theme file with the keys err, success,
+// subtle, title and highlight
+//
+// Parameters:
+//   - nameOrPath: the name of a built in theme, or the path of a theme file
+//
+// Returns the theme
+func LoadTheme(nameOrPath string) (Theme, error) {
+	if theme, ok := Themes[nameOrPath]; ok {
+		return theme, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return Theme{}, WrapError(err, "LoadTheme", "not a built in theme or readable theme file")
+	}
+	var theme Theme
+	err = json.Unmarshal(data, &theme)
+	if err != nil {
+		return Theme{}, WrapError(err, "LoadTheme", "while parsing "+nameOrPath)
+	}
+	return theme, nil
+}
+
+// ansi256
+// Gets the closest xterm 256 color palette index for an RGB color
+func ansi256(r uint8, g uint8, b uint8) int {
+	// Grays use the finer 24 step grayscale ramp
+	if r == g && g == b {
+		if r < 8 {
+			return 16
+		}
+		if r > 248 {
+			return 231
+		}
+		return 232 + (int(r)-8)*24/241
+	}
+	level := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return 16 + 36*level(r) + 6*level(g) + level(b)
+}
+
+// ansi16Palette
+// The usual RGB values of the 16 basic ANSI colors, in code order
+var ansi16Palette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205},
+	{229, 229, 229}, {127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255},
+	{255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// ansi16
+// Gets the closest basic ANSI foreground code (30-37 or 90-97) for an RGB color
+func ansi16(r uint8, g uint8, b uint8) int {
+	best, bestDistance := 0, -1
+	for index, candidate := range ansi16Palette {
+		dr, dg, db := int(r)-candidate[0], int(g)-candidate[1], int(b)-candidate[2]
+		distance := dr*dr + dg*dg + db*db
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	if best < 8 {
+		return 30 + best
+	}
+	return 90 + best - 8
+}
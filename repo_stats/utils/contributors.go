@@ -0,0 +1,84 @@
+package utils
+
+import "sort"
+
+// Contributor
+// The PRs, commits and lines changed of a single contributor
+type Contributor struct {
+	Name         string `json:"name"`
+	PRs          int    `json:"prs"`
+	Commits      int    `json:"commits"`
+	LinesChanged int    `json:"lines_changed"`
+}
+
+// FileSummary
+// The lines, changes and useState calls of a single valid file
+type FileSummary struct {
+	Path string `json:"path"`
+	// False if the file has since been deleted
+	Exists    bool `json:"exists"`
+	Lines     int  `json:"lines"`
+	Changes   int  `json:"changes"`
+	UseStates int  `json:"use_states"`
+}
+
+// Contributors
+// Gets every contributor, most commits first. PRs, commits and lines changed are attributed by
+// GitHub login, commits by authors without a GitHub account by author name
+//
+// Returns array of every contributor
+func (x *Stats) Contributors() []Contributor {
+	lines := x.linesChangedAttribution()
+	names := make(map[string]bool)
+	for _, attribution := range []map[string]int{x.prAttribution, x.commitAttribution, lines} {
+		for name := range attribution {
+			names[name] = true
+		}
+	}
+
+	contributors := make([]Contributor, 0, len(names))
+	for name := range names {
+		contributors = append(contributors, Contributor{Name: name, PRs: x.prAttribution[name],
+			Commits: x.commitAttribution[name], LinesChanged: lines[name]})
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Commits != contributors[j].Commits {
+			return contributors[i].Commits > contributors[j].Commits
+		}
+		if contributors[i].PRs != contributors[j].PRs {
+			return contributors[i].PRs > contributors[j].PRs
+		}
+		return contributors[i].Name < contributors[j].Name
+	})
+	return contributors
+}
+
+// FileSummaries
+// Gets every valid file, including deleted files, ordered by path
+//
+// Returns array of every file
+func (x *Stats) FileSummaries() []FileSummary {
+	sizes := x.filterFiles(x.fileSizes)
+	changes := x.filterFiles(x.fileChanges)
+	useStates := x.filterFiles(x.allUseStates)
+
+	files := make(map[string]bool)
+	for file := range sizes {
+		files[file] = true
+	}
+	for file := range changes {
+		files[file] = true
+	}
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+
+	summaries := make([]FileSummary, 0, len(paths))
+	for _, file := range paths {
+		summaries = append(summaries, FileSummary{Path: file, Exists: x.isExistingFile(file),
+			Lines: sizes[file], Changes: changes[file], UseStates: useStates[file]})
+	}
+	return summaries
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testCommit
+// Creates a commit in the format of GitHub API commits, without an author account if login is
+// empty
+func testCommit(sha string, name string, login string) interface{} {
+	commit := map[string]interface{}{"sha": sha,
+		"commit": map[string]interface{}{"author": map[string]interface{}{"name": name}}}
+	if login != "" {
+		commit["author"] = map[string]interface{}{"login": login}
+	} else {
+		commit["author"] = nil
+	}
+	return commit
+}
+
+func TestContributorsByLogin(t *testing.T) {
+	stats := NewStats("owner", "repo", []string{}, []string{}, []string{})
+	stats.SetPRs([]interface{}{
+		map[string]interface{}{"user": map[string]interface{}{"login": "jdoe"}},
+		map[string]interface{}{"user": map[string]interface{}{"login": "jdoe"}},
+	})
+	stats.SetCommits([]interface{}{
+		testCommit("c3", "Sam Local", ""),
+		testCommit("c2", "Jane D.", "jdoe"),
+		testCommit("c1", "Jane Doe", "jdoe"),
+	})
+	stats.SetFileHistory([]FileChange{
+		{SHA: "c3", Author: "Sam Local", Path: "b.go", CommitPath: "b.go", Status: "added", Changes: 4},
+		{SHA: "c2", Author: "Jane D.", Path: "a.go", CommitPath: "a.go", Status: "modified", Changes: 2},
+		{SHA: "c1", Author: "Jane Doe", Path: "a.go", CommitPath: "a.go", Status: "added", Changes: 10},
+	})
+
+	want := []Contributor{
+		{Name: "jdoe", PRs: 2, Commits: 2, LinesChanged: 12},
+		{Name: "Sam Local", Commits: 1, LinesChanged: 4},
+	}
+	if got := stats.Contributors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Contributors() = %+v, want %+v", got, want)
+	}
+	wantRows := [][]string{{"contributor", "prs", "commits"}, {"jdoe", "2", "2"}, {"Sam Local", "0", "1"}}
+	if got := stats.contributorRows(); !reflect.DeepEqual(got, wantRows) {
+		t.Errorf("contributorRows() = %v, want %v", got, wantRows)
+	}
+}
+
+func TestSetCommitsUpdatesFileHistory(t *testing.T) {
+	stats := NewStats("owner", "repo", []string{}, []string{}, []string{})
+	stats.SetFileHistory([]FileChange{{SHA: "c1", Author: "Jane Doe", Path: "a.go", Changes: 1}})
+	stats.SetCommits([]interface{}{testCommit("c1", "Jane Doe", "jdoe")})
+	if author := stats.fileHistory[0].Author; author != "jdoe" {
+		t.Errorf("Author = %q, want jdoe", author)
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WriteCSV
+// Writes every row of the collected statistics as CSV files: contributors.csv, files.csv,
+// totals.csv and, if teams are set, teams.csv
+//
+// Parameters:
+//   - dir: directory to write the files to, created if missing
+//
+// Returns any errors
+func (x *Stats) WriteCSV(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return WrapError(err, "WriteCSV", "while creating "+dir)
+	}
+
+	err = writeCSVFile(filepath.Join(dir, "contributors.csv"), x.contributorRows())
+	if err != nil {
+		return err
+	}
+	err = writeCSVFile(filepath.Join(dir, "files.csv"), x.fileRows())
+	if err != nil {
+		return err
+	}
+
+	if len(x.teams) > 0 {
+		err = writeCSVFile(filepath.Join(dir, "teams.csv"), x.teamRows())
+		if err != nil {
+			return err
+		}
+	}
+
+	totals := [][]string{{"total", "value"}}
+	for _, total := range x.Report(0).Totals {
+		totals = append(totals, []string{total.Key, strconv.Itoa(total.Value)})
+	}
+	return writeCSVFile(filepath.Join(dir, "totals.csv"), totals)
+}
+
+// contributorRows
+// Gets a header row followed by a row per contributor with their PRs and commits, most
+// commits first, see Contributors
+func (x *Stats) contributorRows() [][]string {
+	rows := [][]string{{"contributor", "prs", "commits"}}
+	for _, contributor := range x.Contributors() {
+		rows = append(rows, []string{contributor.Name, strconv.Itoa(contributor.PRs),
+			strconv.Itoa(contributor.Commits)})
+	}
+	return rows
+}
+
+// fileRows
+// Gets a header row followed by a row per valid file, including deleted files, with
+// lines, changes and useState calls, ordered by path
+func (x *Stats) fileRows() [][]string {
+	rows := [][]string{{"path", "exists", "lines", "changes", "use_states"}}
+	for _, file := range x.FileSummaries() {
+		rows = append(rows, []string{file.Path, strconv.FormatBool(file.Exists),
+			strconv.Itoa(file.Lines), strconv.Itoa(file.Changes), strconv.Itoa(file.UseStates)})
+	}
+	return rows
+}
+
+// teamRows
+// Gets a header row followed by a row per team with their PRs, commits and lines changed,
+// shares of the totals from 0 to 1, and members separated by ";"
+func (x *Stats) teamRows() [][]string {
+	rows := [][]string{{"team", "prs", "pr_share", "commits", "commit_share", "lines_changed",
+		"lines_changed_share", "members"}}
+	for _, team := range x.Teams() {
+		rows = append(rows, []string{team.Team, strconv.Itoa(team.PRs),
+			strconv.FormatFloat(team.PRShare, 'f', 4, 64), strconv.Itoa(team.Commits),
+			strconv.FormatFloat(team.CommitShare, 'f', 4, 64), strconv.Itoa(team.LinesChanged),
+			strconv.FormatFloat(team.LinesChangedShare, 'f', 4, 64), strings.Join(team.Members, ";")})
+	}
+	return rows
+}
+
+// writeCSVFile
+// Creates a file and writes rows to it as CSV
+func writeCSVFile(name string, rows [][]string) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return WrapError(err, "writeCSVFile", "while creating "+name)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return WrapError(err, "writeCSVFile", "while writing "+name)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DirectoryNode
+// Totals of every file inside a directory, with a node for each subdirectory
+type DirectoryNode struct {
+	// The name of the directory, "." for the repository root
+	Name string `json:"name"`
+	// The path of the directory from the repository root
+	Path string `json:"path"`
+	// The number of lines in all files inside the directory
+	Lines int `json:"lines"`
+	// The number of line changes (insertion + deletion) to all files inside the directory
+	Changes int `json:"changes"`
+	// The number of useState calls in all files inside the directory
+	UseStates int `json:"use_states"`
+	// Subdirectories, most lines first
+	Children []*DirectoryNode `json:"children,omitempty"`
+}
+
+// SetDirectoryDepth
+// Sets the number of directory levels kept by DirectoryRollup, deeper files are
+// counted towards their ancestor at that depth
+func (x *Stats) SetDirectoryDepth(depth int) {
+	x.directoryDepth = depth
+}
+
+// DirectoryRollup
+// Sums lines, changes and useState calls of every valid file per directory, up to
+// the directory depth set by SetDirectoryDepth
+// Changes to deleted files are only included if existingOnly is not set
+//
+// Returns pointer to the root directory node
+func (x *Stats) DirectoryRollup() *DirectoryNode {
+	root := &DirectoryNode{Name: ".", Path: "."}
+	for file, lines := range x.filterFiles(x.fileSizes) {
+		for _, node := range root.ancestors(file, x.directoryDepth) {
+			node.Lines += lines
+		}
+	}
+	for file, changes := range x.churn() {
+		for _, node := range root.ancestors(file, x.directoryDepth) {
+			node.Changes += changes
+		}
+	}
+	for file, useStates := range x.filterFiles(x.allUseStates) {
+		for _, node := range root.ancestors(file, x.directoryDepth) {
+			node.UseStates += useStates
+		}
+	}
+	root.sortChildren()
+	return root
+}
+
+// ancestors
+// Gets the nodes of every directory containing file up to depth levels below the root,
+// creating missing nodes
+//
+// Parameters:
+//   - file: path of the file from the repository root
+//   - depth: the deepest directory level to return
+//
+// Returns array of nodes, starting with the root
+func (node *DirectoryNode) ancestors(file string, depth int) []*DirectoryNode {
+	result := []*DirectoryNode{node}
+	dir := path.Dir(file)
+	if dir == "." {
+		return result
+	}
+
+	current := node
+	for index, name := range strings.Split(dir, "/") {
+		if index >= depth {
+			break
+		}
+		var child *DirectoryNode
+		for _, existing := range current.Children {
+			if existing.Name == name {
+				child = existing
+				break
+			}
+		}
+		if child == nil {
+			child = &DirectoryNode{Name: name, Path: path.Join(current.Path, name)}
+			current.Children = append(current.Children, child)
+		}
+		result = append(result, child)
+		current = child
+	}
+	return result
+}
+
+// sortChildren
+// Sorts the children of every node by lines, then by name
+func (node *DirectoryNode) sortChildren() {
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Lines != node.Children[j].Lines {
+			return node.Children[i].Lines > node.Children[j].Lines
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	for _, child := range node.Children {
+		child.sortChildren()
+	}
+}
+
+// percentOf
+// Gets value as a percentage of total, 0 if total is 0
+func percentOf(value int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+// printDirectories
+// Prints a directory tree, indenting each level, with percentages of the root totals
+//
+// Parameters:
+//   - root: the root directory node
+func printDirectories(root *DirectoryNode) {
+	var printNode func(node *DirectoryNode, level int)
+	printNode = func(node *DirectoryNode, level int) {
+		OutputFrom([]string{strings.Repeat("  ", level) + node.Name,
+			fmt.Sprintf("%d lines (%.1f%%)", node.Lines, percentOf(node.Lines, root.Lines)),
+			fmt.Sprintf("%d changes (%.1f%%)", node.Changes, percentOf(node.Changes, root.Changes)),
+			fmt.Sprintf("%d useState", node.UseStates)},
+			[]Color{Highlight, TitleNoBold, Subtle, Subtle})
+		for _, child := range node.Children {
+			printNode(child, level+1)
+		}
+	}
+	printNode(root, 0)
+	fmt.Fprintln(output)
+}
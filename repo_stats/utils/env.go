@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadEnv
+// Reads in env vars from a file, places them into a map. Follows dotenv syntax:
+//   - blank lines and lines starting with # are skipped, as is an export prefix
+//   - unquoted values are trimmed and end at a # preceded by a space
+//   - single quoted values are kept as is
+//   - double quoted values support \n, \r, \t, \", \\ and \$ escapes
+//   - quoted values may span several lines
+//   - ${VAR} is replaced in unquoted and double quoted values, by a variable set earlier in the
+//     file, otherwise by the environment variable, otherwise by nothing
+//
+// Parameters:
+//   - file: Reader for environment file
+//
+// Returns map which maps env var to assignment, or an error with the line of any syntax error
+func ReadEnv(file io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, WrapError(err, "ReadEnv", "while reading env file")
+	}
+	parser := envParser{text: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1,
+		envs: make(map[string]string)}
+	err = parser.parse()
+	if err != nil {
+		return nil, WrapError(err, "ReadEnv", "while parsing env file")
+	}
+	return parser.envs, nil
+}
+
+// envParser
+// Parses the text of an env file one character at a time, tracking the current line
+type envParser struct {
+	text string
+	pos  int
+	line int
+	envs map[string]string
+}
+
+// parse
+// Parses every assignment into envs
+func (p *envParser) parse() error {
+	for {
+		p.skipBlanks()
+		if p.pos >= len(p.text) {
+			return nil
+		}
+		switch p.text[p.pos] {
+		case '\n':
+			p.advance()
+			continue
+		case '#':
+			p.skipComment()
+			continue
+		}
+
+		line := p.line
+		key := p.readKey()
+		if key == "export" && p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+			p.skipBlanks()
+			key = p.readKey()
+		}
+		if key == "" {
+			return p.errorf(line, "expected a variable name, found %q", p.rest())
+		}
+		p.skipBlanks()
+		if p.pos >= len(p.text) || p.text[p.pos] != '=' {
+			return p.errorf(line, "expected = after %s", key)
+		}
+		p.advance()
+		p.skipBlanks()
+
+		value, err := p.readValue()
+		if err != nil {
+			return err
+		}
+		p.envs[key] = value
+	}
+}
+
+// readKey
+// Reads a variable name, made of letters, digits, underscores, dots and dashes
+func (p *envParser) readKey() string {
+	start := p.pos
+	for p.pos < len(p.text) {
+		char := p.text[p.pos]
+		if !(char == '_' || char == '.' || char == '-' || (char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')) {
+			break
+		}
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// readValue
+// Reads the value of an assignment along with the rest of its line
+func (p *envParser) readValue() (string, error) {
+	if p.pos >= len(p.text) || (p.text[p.pos] != '\'' && p.text[p.pos] != '"') {
+		return p.readUnquoted()
+	}
+
+	quote := p.text[p.pos]
+	line := p.line
+	p.advance()
+	var value strings.Builder
+	for {
+		if p.pos >= len(p.text) {
+			return "", p.errorf(line, "unterminated %c quoted value", quote)
+		}
+		char := p.text[p.pos]
+		switch {
+		case char == quote:
+			p.advance()
+			return value.String(), p.endLine()
+		case quote == '\'':
+			value.WriteByte(char)
+			p.advance()
+		case char == '\\' && p.pos+1 < len(p.text):
+			value.WriteString(unescapeEnv(p.text[p.pos+1]))
+			p.advance()
+			p.advance()
+		case char == '$' && strings.HasPrefix(p.text[p.pos:], "${"):
+			expanded, err := p.readExpansion()
+			if err != nil {
+				return "", err
+			}
+			value.WriteString(expanded)
+		default:
+			value.WriteByte(char)
+			p.advance()
+		}
+	}
+}
+
+// readUnquoted
+// Reads an unquoted value, which ends at the end of the line or at a comment
+func (p *envParser) readUnquoted() (string, error) {
+	var value strings.Builder
+	for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+		char := p.text[p.pos]
+		if char == '#' && (value.Len() == 0 || strings.HasSuffix(value.String(), " ") ||
+			strings.HasSuffix(value.String(), "\t")) {
+			p.skipComment()
+			break
+		}
+		if char == '$' && strings.HasPrefix(p.text[p.pos:], "${") {
+			expanded, err := p.readExpansion()
+			if err != nil {
+				return "", err
+			}
+			value.WriteString(expanded)
+			continue
+		}
+		value.WriteByte(char)
+		p.advance()
+	}
+	return strings.TrimRight(value.String(), " \t"), nil
+}
+
+// readExpansion
+// Reads a ${VAR} reference and gets its value
+func (p *envParser) readExpansion() (string, error) {
+	end := strings.IndexAny(p.text[p.pos:], "}\n")
+	if end == -1 || p.text[p.pos+end] != '}' {
+		return "", p.errorf(p.line, "unterminated ${ in value")
+	}
+	name := p.text[p.pos+2 : p.pos+end]
+	p.pos += end + 1
+	if value, ok := p.envs[name]; ok {
+		return value, nil
+	}
+	return os.Getenv(name), nil
+}
+
+// endLine
+// Skips the rest of the line after a quoted value, which may only hold a comment
+func (p *envParser) endLine() error {
+	p.skipBlanks()
+	if p.pos >= len(p.text) || p.text[p.pos] == '\n' {
+		return nil
+	}
+	if p.text[p.pos] == '#' {
+		p.skipComment()
+		return nil
+	}
+	return p.errorf(p.line, "unexpected %q after closing quote", p.rest())
+}
+
+// unescapeEnv
+// Gets the text of an escape sequence in a double quoted value, unknown sequences are kept as is
+func unescapeEnv(char byte) string {
+	switch char {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '"', '\\', '$':
+		return string(char)
+	}
+	return "\\" + string(char)
+}
+
+// advance
+// Moves to the next character, counting lines
+func (p *envParser) advance() {
+	if p.text[p.pos] == '\n' {
+		p.line++
+	}
+	p.pos++
+}
+
+// skipBlanks
+// Skips spaces and tabs, but not new lines
+func (p *envParser) skipBlanks() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment
+// Skips to the end of the line, leaving the new line itself
+func (p *envParser) skipComment() {
+	for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// rest
+// Gets the rest of the current line, for error messages
+func (p *envParser) rest() string {
+	end := strings.IndexByte(p.text[p.pos:], '\n')
+	if end == -1 {
+		return p.text[p.pos:]
+	}
+	return p.text[p.pos : p.pos+end]
+}
+
+// errorf
+// Creates a syntax error on a line
+func (p *envParser) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEnv(t *testing.T) {
+	t.Setenv("REPO_STATS_TEST_HOME", "/home/test")
+
+	tests := []struct {
+		name string
+		file string
+		want map[string]string
+	}{
+		{
+			name: "plain",
+			file: "TOKEN=abc\nOWNER = octo \n",
+			want: map[string]string{"TOKEN": "abc", "OWNER": "octo"},
+		},
+		{
+			name: "blank lines, comments and export",
+			file: "# comment\n\n  # indented comment\nexport TOKEN=abc\r\nexport=kept\n",
+			want: map[string]string{"TOKEN": "abc", "export": "kept"},
+		},
+		{
+			name: "inline comments",
+			file: "A=value # comment\nB=val#ue\nC=#comment\nD='quoted' # comment\n",
+			want: map[string]string{"A": "value", "B": "val#ue", "C": "", "D": "quoted"},
+		},
+		{
+			name: "single quotes are kept as is",
+			file: `A='a "b" \n ${REPO_STATS_TEST_HOME} # c'` + "\n",
+			want: map[string]string{"A": `a "b" \n ${REPO_STATS_TEST_HOME} # c`},
+		},
+		{
+			name: "double quote escapes",
+			file: `A="line\nnext\ttab \"quoted\" back\\slash \$HOME \q"` + "\n",
+			want: map[string]string{"A": "line\nnext\ttab \"quoted\" back\\slash $HOME \\q"},
+		},
+		{
+			name: "multiline",
+			file: "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nAFTER='x\ny'\n",
+			want: map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "AFTER": "x\ny"},
+		},
+		{
+			name: "expansion",
+			file: "HOST=example.com\nURL=https://${HOST}/api\nQUOTED=\"${HOST} # not a comment\"\n" +
+				"HOME_DIR=${REPO_STATS_TEST_HOME}\nMISSING=[${REPO_STATS_TEST_UNSET}]\n",
+			want: map[string]string{"HOST": "example.com", "URL": "https://example.com/api",
+				"QUOTED": "example.com # not a comment", "HOME_DIR": "/home/test", "MISSING": "[]"},
+		},
+		{
+			name: "later assignments win",
+			file: "A=1\nA=2\n",
+			want: map[string]string{"A": "2"},
+		},
+		{
+			name: "empty",
+			file: "",
+			want: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ReadEnv(strings.NewReader(test.file))
+			if err != nil {
+				t.Fatalf("ReadEnv() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ReadEnv() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadEnvErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		line string
+	}{
+		{name: "missing =", file: "A=1\nTOKEN abc\n", line: "line 2:"},
+		{name: "missing name", file: "=abc\n", line: "line 1:"},
+		{name: "unterminated quote", file: "A=1\n\nB=\"abc\nC=2\n", line: "line 3:"},
+		{name: "text after quote", file: "A='x' y\n", line: "line 1:"},
+		{name: "after multiline value", file: "A=\"x\ny\"\nB\n", line: "line 3:"},
+		{name: "unterminated expansion", file: "A=${B\n", line: "line 1:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadEnv(strings.NewReader(test.file))
+			if err == nil {
+				t.Fatal("ReadEnv() error = nil, want an error")
+			}
+			if !strings.Contains(err.Error(), test.line) {
+				t.Errorf("ReadEnv() error = %q, want it to contain %q", err, test.line)
+			}
+		})
+	}
+}
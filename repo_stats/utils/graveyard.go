@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Graveyard
+// Files which were changed in the past but are no longer in the analyzed tree
+type Graveyard struct {
+	// The number of deleted files
+	DeletedFiles int `json:"deleted_files"`
+	// The number of lines ever deleted from any file, deleted or not
+	LinesDeleted int `json:"lines_deleted"`
+	// Deleted files ranked by line changes (insertion + deletion)
+	Files []RankedItem `json:"files"`
+}
+
+// SetExistingOnly
+// Sets whether churn rankings only include files present in the analyzed tree
+func (x *Stats) SetExistingOnly(existingOnly bool) {
+	x.existingOnly = existingOnly
+}
+
+// churn
+// Gets the valid file changes used by churn rankings, only including files in the
+// analyzed tree if existingOnly is set
+//
+// Returns map of file path to number of line changes
+func (x *Stats) churn() map[string]int {
+	result := x.filterFiles(x.fileChanges)
+	if !x.existingOnly {
+		return result
+	}
+	for file := range result {
+		if !x.isExistingFile(file) {
+			delete(result, file)
+		}
+	}
+	return result
+}
+
+// isExistingFile
+// Checks if a file is present in the analyzed tree
+func (x *Stats) isExistingFile(file string) bool {
+	_, ok := x.fileURLs[file]
+	return ok
+}
+
+// Graveyard
+// Gets the most changed files which are no longer in the analyzed tree
+//
+// Parameters:
+//   - n: the number of files to keep, 0 or less to keep every file
+//
+// Returns pointer to new Graveyard
+func (x *Stats) Graveyard(n int) *Graveyard {
+	deleted := make(map[string]int)
+	for file, changes := range x.filterFiles(x.fileChanges) {
+		if !x.isExistingFile(file) {
+			deleted[file] = changes
+		}
+	}
+
+	linesDeleted := 0
+	for _, change := range x.fileHistory {
+		linesDeleted += change.Deletions
+	}
+
+	return &Graveyard{DeletedFiles: len(deleted), LinesDeleted: linesDeleted,
+		Files: rankedItems(deleted, n)}
+}
+
+// printGraveyard
+// Prints graveyard totals followed by the deleted files in order, prefixed by a number
+//
+// Parameters:
+//   - graveyard: the graveyard to print
+func printGraveyard(graveyard *Graveyard) {
+	OutputFrom([]string{"Deleted files:", strconv.Itoa(graveyard.DeletedFiles)},
+		[]Color{Subtle, Highlight})
+	OutputFrom([]string{"Lines ever deleted:", strconv.Itoa(graveyard.LinesDeleted)},
+		[]Color{Subtle, Highlight})
+	for index, file := range graveyard.Files {
+		OutputFrom([]string{strconv.Itoa(index + 1), file.Name, strconv.Itoa(file.Value)},
+			[]Color{Subtle, Highlight, Subtle})
+	}
+	fmt.Fprintln(output)
+}
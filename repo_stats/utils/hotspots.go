@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Hotspot
+// Represents a file which is both large and frequently changed
+type Hotspot struct {
+	// The path of the file
+	Path string `json:"path"`
+	// The number of lines in the file
+	Lines int `json:"lines"`
+	// The number of line changes (insertion + deletion) total
+	Changes int `json:"changes"`
+	// The number of distinct commit authors who changed the file
+	Authors int `json:"authors"`
+	// Normalized churn multiplied by normalized size, between 0 and 1
+	Score float64 `json:"score"`
+}
+
+// Hotspots
+// Ranks every file present in both fileSizes and fileChanges by normalized churn x size,
+// using the number of distinct authors to break ties
+//
+// Returns array of all hotspots, highest score first
+func (x *Stats) Hotspots() []Hotspot {
+	sizes := x.filterFiles(x.fileSizes)
+	changes := x.filterFiles(x.fileChanges)
+
+	authors := make(map[string]map[string]bool)
+	for _, change := range x.fileHistory {
+		if change.Author == mergeAuthor {
+			continue
+		}
+		if authors[change.Path] == nil {
+			authors[change.Path] = make(map[string]bool)
+		}
+		authors[change.Path][change.Author] = true
+	}
+
+	maxLines, maxChanges := 0, 0
+	for file, fileChanges := range changes {
+		if _, ok := sizes[file]; !ok {
+			continue
+		}
+		maxLines = max(maxLines, sizes[file])
+		maxChanges = max(maxChanges, fileChanges)
+	}
+
+	result := make([]Hotspot, 0)
+	if maxLines == 0 || maxChanges == 0 {
+		return result
+	}
+	for file, fileChanges := range changes {
+		lines, ok := sizes[file]
+		if !ok {
+			continue
+		}
+		score := (float64(fileChanges) / float64(maxChanges)) * (float64(lines) / float64(maxLines))
+		result = append(result, Hotspot{Path: file, Lines: lines, Changes: fileChanges,
+			Authors: len(authors[file]), Score: score})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		if result[i].Authors != result[j].Authors {
+			return result[i].Authors > result[j].Authors
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// TopHotspots
+// Gets the top n hotspots (in order)
+func (x *Stats) TopHotspots(n int) []Hotspot {
+	result := x.Hotspots()
+	if n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// printHotspots
+// Prints hotspots in order, prefixed by a number
+//
+// Parameters:
+//   - hotspots: array of hotspots, already in order
+func printHotspots(hotspots []Hotspot) {
+	for index, hotspot := range hotspots {
+		OutputFrom([]string{strconv.Itoa(index + 1), hotspot.Path,
+			fmt.Sprintf("%.2f", hotspot.Score),
+			fmt.Sprintf("(%d lines, %d changes, %d authors)", hotspot.Lines, hotspot.Changes, hotspot.Authors)},
+			[]Color{Subtle, Highlight, TitleNoBold, Subtle})
+	}
+	fmt.Fprintln(output)
+}
@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// chartPalette
+// Fill colors used for chart series, in order
+var chartPalette = []string{"#00ffff", "#9966cc", "#7fffd4", "#cd2949", "#f4c542", "#4f8cff",
+	"#ff8c42", "#8fd14f", "#ff6fb5", "#696969"}
+
+// WriteHTML
+// Writes the report as a single self-contained HTML page, with embedded CSS and inline SVG
+// charts, which needs no external assets
+//
+// Parameters:
+//   - w: writer to output the report to
+//
+// Returns any errors
+func (r *Report) WriteHTML(w io.Writer) error {
+	page, err := template.New("report").Funcs(template.FuncMap{
+		"barChart":      barChart,
+		"activityChart": activityChart,
+		"languageChart": languageChart,
+		"percentOf":     percentOf,
+		"add":           func(a int, b int) int { return a + b },
+		"indent":        func(level int) int { return 8 + level*20 },
+		"share":         func(share float64) float64 { return share * 100 },
+		"flatten":       flattenDirectories,
+		"ownership": func(title string, items []Ownership) map[string]interface{} {
+			return map[string]interface{}{"Title": title, "Items": items}
+		},
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return WrapError(err, "WriteHTML", "while parsing template")
+	}
+	return page.Execute(w, r)
+}
+
+// directoryRow
+// A single directory in a flattened directory tree
+type directoryRow struct {
+	Level int
+	Node  *DirectoryNode
+	Root  *DirectoryNode
+}
+
+// flattenDirectories
+// Flattens a directory tree into rows in display order
+func flattenDirectories(root *DirectoryNode) []directoryRow {
+	rows := make([]directoryRow, 0)
+	var walk func(node *DirectoryNode, level int)
+	walk = func(node *DirectoryNode, level int) {
+		rows = append(rows, directoryRow{Level: level, Node: node, Root: root})
+		for _, child := range node.Children {
+			walk(child, level+1)
+		}
+	}
+	if root != nil {
+		walk(root, 0)
+	}
+	return rows
+}
+
+// truncate
+// Shortens text to at most n characters, keeping the end which is the most specific
+// part of a file path
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return "…" + string(runes[len(runes)-n+1:])
+}
+
+// barChart
+// Renders ranked items as an inline SVG horizontal bar chart
+func barChart(items []RankedItem) template.HTML {
+	if len(items) == 0 {
+		return template.HTML(`<p class="empty">Nothing to show</p>`)
+	}
+
+	const width, rowHeight, labelWidth, valueWidth = 560, 30, 220, 60
+	maxValue := 1
+	for _, item := range items {
+		maxValue = max(maxValue, item.Value)
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="chart" viewBox="0 0 %d %d" role="img">`, width, len(items)*rowHeight)
+	for index, item := range items {
+		y := index * rowHeight
+		barWidth := max(2, (width-labelWidth-valueWidth)*item.Value/maxValue)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" class="label" text-anchor="end">%s</text>`,
+			labelWidth-8, y+19, template.HTMLEscapeString(truncate(item.Name, 30)))
+		fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" rx="4" fill="%s"><title>%s</title></rect>`,
+			labelWidth, y+6, barWidth, rowHeight-12, chartPalette[index%2],
+			template.HTMLEscapeString(item.Name))
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" class="value">%d</text>`,
+			labelWidth+barWidth+6, y+19, item.Value)
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// activityChart
+// Renders commits and PRs per month as an inline SVG grouped column chart
+func activityChart(activity []ActivityPeriod) template.HTML {
+	if len(activity) == 0 {
+		return template.HTML(`<p class="empty">Nothing to show</p>`)
+	}
+
+	const width, height, bottom, top = 900, 260, 40, 16
+	maxValue := 1
+	for _, period := range activity {
+		maxValue = max(maxValue, period.Commits, period.PRs)
+	}
+	slot := float64(width) / float64(len(activity))
+	barWidth := max(1.0, slot*0.4)
+	// Only label every few months so labels do not overlap
+	labelEvery := max(1, len(activity)/12)
+	scale := float64(height-bottom-top) / float64(maxValue)
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="chart" viewBox="0 0 %d %d" role="img">`, width, height)
+	fmt.Fprintf(&svg, `<line x1="0" y1="%d" x2="%d" y2="%d" class="axis"/>`, height-bottom, width, height-bottom)
+	for index, period := range activity {
+		x := float64(index) * slot
+		commitHeight := float64(period.Commits) * scale
+		prHeight := float64(period.PRs) * scale
+		fmt.Fprintf(&svg, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %d commits</title></rect>`,
+			x+slot*0.1, float64(height-bottom)-commitHeight, barWidth, commitHeight, chartPalette[0],
+			period.Period, period.Commits)
+		fmt.Fprintf(&svg, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %d PRs</title></rect>`,
+			x+slot*0.1+barWidth, float64(height-bottom)-prHeight, barWidth, prHeight, chartPalette[1],
+			period.Period, period.PRs)
+		if index%labelEvery == 0 {
+			fmt.Fprintf(&svg, `<text x="%.1f" y="%d" class="label" text-anchor="middle">%s</text>`,
+				x+slot/2, height-bottom+20, period.Period)
+		}
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// languageChart
+// Renders lines per language as an inline SVG stacked bar with a legend
+func languageChart(languages []RankedItem) template.HTML {
+	total := 0
+	for _, language := range languages {
+		total += language.Value
+	}
+	if total == 0 {
+		return template.HTML(`<p class="empty">Nothing to show</p>`)
+	}
+
+	const width, barHeight, legendRow = 900, 36, 26
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg class="chart" viewBox="0 0 %d %d" role="img">`,
+		width, barHeight+16+legendRow*((len(languages)+2)/3))
+	x := 0.0
+	for index, language := range languages {
+		segment := float64(width) * float64(language.Value) / float64(total)
+		fmt.Fprintf(&svg, `<rect x="%.1f" y="0" width="%.1f" height="%d" fill="%s"><title>%s</title></rect>`,
+			x, segment, barHeight, chartPalette[index%len(chartPalette)],
+			template.HTMLEscapeString(language.Name))
+		x += segment
+	}
+	for index, language := range languages {
+		legendX := (index % 3) * (width / 3)
+		legendY := barHeight + 16 + (index/3)*legendRow
+		fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="14" height="14" rx="3" fill="%s"/>`,
+			legendX, legendY, chartPalette[index%len(chartPalette)])
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" class="label">%s %.1f%%</text>`,
+			legendX+22, legendY+12, template.HTMLEscapeString(language.Name),
+			percentOf(language.Value, total))
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// htmlTemplate
+// The page rendered by WriteHTML
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Repo}} Wrapped</title>
+<style>
+  body { margin: 0; background: #111318; color: #e6e6e6; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; }
+  main { max-width: 960px; margin: 0 auto; padding: 32px 24px 64px; }
+  h1 { color: #00ffff; font-size: 2.4em; margin: 0; }
+  h2 { color: #00ffff; margin-top: 48px; border-bottom: 1px solid #2a2d36; padding-bottom: 8px; }
+  h3 { color: #9966cc; margin-bottom: 4px; }
+  .subtle, .empty { color: #8a8a8a; }
+  .totals { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 16px; margin-top: 24px; }
+  .total { background: #1b1e26; border-radius: 12px; padding: 20px; }
+  .total .value { font-size: 2.2em; font-weight: bold; color: #7fffd4; }
+  .total .label { color: #8a8a8a; }
+  .boards { display: grid; grid-template-columns: repeat(auto-fit, minmax(420px, 1fr)); gap: 24px; }
+  .board { background: #1b1e26; border-radius: 12px; padding: 8px 20px 16px; }
+  .chart { width: 100%; height: auto; }
+  .chart .label { fill: #c8c8c8; font-size: 13px; }
+  .chart .value { fill: #8a8a8a; font-size: 13px; }
+  .chart .axis { stroke: #3a3d46; }
+  .legend span { display: inline-block; width: 12px; height: 12px; border-radius: 3px; margin: 0 6px 0 16px; }
+  table { width: 100%; border-collapse: collapse; font-size: 0.92em; }
+  th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #2a2d36; }
+  th { color: #8a8a8a; font-weight: normal; }
+  td.num, th.num { text-align: right; }
+  code { color: #9966cc; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Repo}} Wrapped</h1>
+<p class="subtle">Generated {{.GeneratedAt.Format "January 2, 2006 15:04 MST"}}</p>
+
+<div class="totals">
+{{- range .Totals}}
+  <div class="total"><div class="value">{{.Value}}</div><div class="label">{{.Label}}</div></div>
+{{- end}}
+</div>
+
+{{- if .Directories}}
+<h2>Activity</h2>
+<p class="legend"><span style="background: #00ffff"></span>Commits<span style="background: #9966cc"></span>PRs</p>
+{{activityChart .Activity}}
+{{- end}}
+
+<h2>Leaderboards</h2>
+<div class="boards">
+{{- range .Rankings}}
+  <div class="board"><h3>{{.Title}}</h3>{{barChart .Items}}</div>
+{{- end}}
+</div>
+
+{{- range .Groups}}
+<h2>{{.Title}}</h2>
+<div class="totals">
+{{- range .Totals}}
+  <div class="total"><div class="value">{{.Value}}</div><div class="label">{{.Label}}</div></div>
+{{- end}}
+</div>
+<div class="boards">
+{{- range .Rankings}}
+  <div class="board"><h3>{{.Title}}</h3>{{barChart .Items}}</div>
+{{- end}}
+</div>
+{{- end}}
+
+{{- if .Directories}}
+<h2>Languages</h2>
+{{languageChart .Languages}}
+
+<h2>Hotspots</h2>
+<p class="subtle">Files which are both large and frequently changed, the most likely to need refactoring.</p>
+<table>
+  <tr><th class="num">#</th><th>File</th><th class="num">Score</th><th class="num">Lines</th><th class="num">Changes</th><th class="num">Authors</th></tr>
+{{- range $index, $hotspot := .Hotspots}}
+  <tr><td class="num">{{add $index 1}}</td><td><code>{{$hotspot.Path}}</code></td><td class="num">{{printf "%.2f" $hotspot.Score}}</td><td class="num">{{$hotspot.Lines}}</td><td class="num">{{$hotspot.Changes}}</td><td class="num">{{$hotspot.Authors}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- with .Graveyard}}
+<h2>Graveyard</h2>
+<p class="subtle">{{.DeletedFiles}} deleted files, {{.LinesDeleted}} lines ever deleted.</p>
+<div class="board">{{barChart .Files}}</div>
+{{- end}}
+
+{{- with .Directories}}
+<h2>Directories</h2>
+<table>
+  <tr><th>Directory</th><th class="num">Lines</th><th class="num">% Lines</th><th class="num">Changes</th><th class="num">% Changes</th><th class="num">useState</th></tr>
+{{- range flatten .}}
+  <tr><td style="padding-left: {{indent .Level}}px"><code>{{.Node.Name}}</code></td><td class="num">{{.Node.Lines}}</td><td class="num">{{printf "%.1f%%" (percentOf .Node.Lines .Root.Lines)}}</td><td class="num">{{.Node.Changes}}</td><td class="num">{{printf "%.1f%%" (percentOf .Node.Changes .Root.Changes)}}</td><td class="num">{{.Node.UseStates}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Renames}}
+<h2>Rename History</h2>
+<table>
+  <tr><th>File</th><th>Previous Paths (newest first)</th></tr>
+{{- range .Renames}}
+  <tr><td><code>{{.Path}}</code></td><td>{{range $index, $path := .PreviousPaths}}{{if $index}} &larr; {{end}}<code>{{$path}}</code>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Teams}}
+<h2>Teams</h2>
+<table>
+  <tr><th>Team</th><th class="num">PRs</th><th class="num">% PRs</th><th class="num">Commits</th><th class="num">% Commits</th><th class="num">Lines Changed</th><th class="num">% Lines Changed</th><th>Members</th></tr>
+{{- range .Teams}}
+  <tr><td>{{.Team}}</td><td class="num">{{.PRs}}</td><td class="num">{{printf "%.1f%%" (share .PRShare)}}</td><td class="num">{{.Commits}}</td><td class="num">{{printf "%.1f%%" (share .CommitShare)}}</td><td class="num">{{.LinesChanged}}</td><td class="num">{{printf "%.1f%%" (share .LinesChangedShare)}}</td><td>{{range $index, $member := .Members}}{{if $index}}, {{end}}{{$member}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+{{- if .Directories}}
+<h2>Ownership</h2>
+{{- template "ownership" (ownership "Directory Ownership" .DirectoryOwnership)}}
+{{- template "ownership" (ownership "Knowledge Silos (one author owns 90%+ of changes)" .KnowledgeSilos)}}
+{{- template "ownership" (ownership "File Ownership" .FileOwnership)}}
+{{- end}}
+</main>
+</body>
+</html>
+{{- define "ownership"}}
+<h3>{{.Title}}</h3>
+<table>
+  <tr><th class="num">#</th><th>Path</th><th class="num">Bus Factor</th><th>Top Owner</th><th class="num">Share</th><th class="num">Changes</th></tr>
+{{- range $index, $ownership := .Items}}
+  {{- $top := index $ownership.Owners 0}}
+  <tr><td class="num">{{add $index 1}}</td><td><code>{{$ownership.Path}}</code></td><td class="num">{{$ownership.BusFactor}}</td><td>{{$top.Author}}</td><td class="num">{{printf "%.0f%%" (share $top.Share)}}</td><td class="num">{{$ownership.Changes}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+`
@@ -7,30 +7,25 @@ import (
 	"golang.org/x/term"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 )
 
-type Color struct {
-	code string
-}
+// output
+// The writer all output functions print to, defaults to os.Stdout
+var output io.Writer = os.Stdout
 
-func (c Color) String() string {
-	return c.code
+// SetOutput
+// Sets the writer all output functions print to
+//
+// Parameters:
+//   - w: writer to print to, such as os.Stderr when stdout is reserved for structured output
+func SetOutput(w io.Writer) {
+	output = w
 }
 
-// Predefined color constants
-var (
-	Err         = Color{"\033[38;2;205;41;73m"}
-	Success     = Color{"\033[38;2;127;255;212m"}
-	Subtle      = Color{"\033[38;2;105;105;105m"}
-	Title       = Color{"\u001B[1m\033[38;2;0;255;255m"}
-	TitleNoBold = Color{"\033[38;2;0;255;255m"}
-	Highlight   = Color{"\033[38;2;153;102;204m"}
-	End         = Color{"\033[0m"}
-	None        = Color{""}
-)
-
 // GetInput
 // get input from the user
 //
@@ -57,7 +52,7 @@ func GetInput(prompt string, promptColor Color) string {
 func GetInputAndRespond(prompt string, promptColor Color,
 	response string, responseType Color) string {
 	if prompt != "" {
-		fmt.Printf("%s%s: %s", promptColor, prompt, End)
+		fmt.Fprintf(output, "%s%s: %s", promptColor, prompt, End)
 	}
 	var input string
 	_, err := fmt.Scanln(&input)
@@ -65,7 +60,7 @@ func GetInputAndRespond(prompt string, promptColor Color,
 		return ""
 	}
 	if response != "" {
-		fmt.Printf("%s%s%s\n", responseType, response, End)
+		fmt.Fprintf(output, "%s%s%s\n", responseType, response, End)
 	}
 	return input
 }
@@ -89,10 +84,10 @@ func Output(message string, messageColor Color) {
 //   - messageColor: The Color of the message
 func OutputWithTitle(title string, titleColor Color, message string, messageColor Color) {
 	if title != "" {
-		fmt.Printf("\033[1m%s%s%s\n", titleColor, title, End)
+		fmt.Fprintf(output, "%s%s%s%s\n", Bold, titleColor, title, End)
 	}
 
-	fmt.Printf("%s%s%s\n", messageColor, message, End)
+	fmt.Fprintf(output, "%s%s%s\n", messageColor, message, End)
 }
 
 // OutputFrom
@@ -114,14 +109,14 @@ func OutputFrom(messageItems []string, messageColors []Color) error {
 	for index := range messageItems {
 		message := messageItems[index]
 		messageColor := messageColors[index]
-		fmt.Printf("%s%s%s", messageColor, message, End)
+		fmt.Fprintf(output, "%s%s%s", messageColor, message, End)
 		if index != len(messageItems)-1 {
-			fmt.Print(" ")
+			fmt.Fprint(output, " ")
 		}
 	}
 
 	// Print newline at the end of message
-	fmt.Println()
+	fmt.Fprintln(output)
 
 	return nil
 }
@@ -145,7 +140,7 @@ func AnimatedLoader(text string, textColor Color, stopCh chan bool) {
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			fmt.Printf("\r%s%s%s%s%s\r", textColor, text,
+			fmt.Fprintf(output, "\r%s%s%s%s%s\r", textColor, text,
 				strings.Repeat(".", count), strings.Repeat(" ", 3-count), End)
 			count++
 			if count > 3 {
@@ -188,7 +183,7 @@ func ProgressLoader(text string, textColor Color, total int) func(string) {
 		progressCount := int(float32(barWidth) * progressPercent)
 		remainingCount := barWidth - progressCount
 
-		fmt.Printf("\r%s%s%s %s[%s%s]%s",
+		fmt.Fprintf(output, "\r%s%s%s %s[%s%s]%s",
 			textColor, _text, End,
 			Subtle,
 			strings.Repeat("#", progressCount),
@@ -211,36 +206,89 @@ func ProgressLoader(text string, textColor Color, total int) func(string) {
 func UpdatableOutputter() (func(), func(string, Color)) {
 	maxOutput := 0
 	return func() {
-			fmt.Print("\r" + strings.Repeat(" ", maxOutput))
+			fmt.Fprint(output, "\r"+strings.Repeat(" ", maxOutput))
 		},
 		func(text string, textColor Color) {
 			// Clear old text first, then print new text
-			fmt.Printf("\r%s\r%s%s%s", strings.Repeat(" ", maxOutput), textColor, text, End)
+			fmt.Fprintf(output, "\r%s\r%s%s%s", strings.Repeat(" ", maxOutput), textColor, text, End)
 			maxOutput = max(maxOutput, len(text))
 		}
 }
 
-// ReadEnv
-// Reads in env vars from a file, places them into a map
+// GHHostsPath
+// Gets the hosts.yml file the GitHub CLI keeps its tokens in, under GH_CONFIG_DIR,
+// XDG_CONFIG_HOME/gh, AppData/GitHub CLI on Windows, or ~/.config/gh
+func GHHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// ReadGHToken
+// Reads the token of a host from the GitHub CLI's hosts.yml. Only the top level oauth_token of
+// the host is read, the CLI keeps it in the system keyring instead unless logged in with
+// --insecure-storage
 //
 // Parameters:
-//   - file: Reader for environment file
+//   - file: Reader for hosts.yml
+//   - host: the host to read the token of, ex: github.com
 //
-// Returns map which maps env var to assignment
-func ReadEnv(file io.Reader) (map[string]string, error) {
-	envs := make(map[string]string)
+// Returns the token, empty if the host has none
+func ReadGHToken(file io.Reader, host string) (string, error) {
 	scanner := bufio.NewScanner(file)
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
+	inHost := false
+	keyIndent := -1
 	for scanner.Scan() {
-		line := scanner.Text()
-		items := strings.SplitN(line, "=", 2)
-		if len(items) != 2 {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
 			continue
 		}
-		// Trim spaces and assign a variable to its item
-		envs[strings.Trim(items[0], " ")] = strings.Trim(items[1], " ")
+
+		// Hosts are the top level keys, their settings are indented below them
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			inHost = unquoteYAML(strings.TrimSuffix(trimmed, ":")) == host
+			keyIndent = -1
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		if keyIndent == -1 {
+			keyIndent = indent
+		}
+		if indent != keyIndent {
+			// Settings nested deeper, such as the tokens of each user under users
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if ok && key == "oauth_token" {
+			return unquoteYAML(strings.TrimSpace(value)), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", WrapError(err, "ReadGHToken", "while reading hosts.yml")
+	}
+	return "", nil
+}
+
+// unquoteYAML
+// Removes the quotes around a YAML scalar, if any
+func unquoteYAML(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
 	}
-	return envs, nil
+	return value
 }
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepoSummary
+// The totals of a single repository compared in a Leaderboard
+type RepoSummary struct {
+	// The repository, as owner/name
+	Repo        string `json:"repo"`
+	Commits     int    `json:"commits"`
+	PRs         int    `json:"prs"`
+	LinesOfCode int    `json:"lines_of_code"`
+	// The number of distinct commit authors
+	Contributors int `json:"contributors"`
+	// The total line changes to every valid file
+	Churn int `json:"churn"`
+}
+
+// Leaderboard
+// A comparison of several repositories ranked against each other
+type Leaderboard struct {
+	// The time the leaderboard was generated
+	GeneratedAt time.Time `json:"generated_at"`
+	// The totals of every repository, in the order given
+	Repos []RepoSummary `json:"repos"`
+	// The repositories ranked by each total, in display order
+	Rankings []Ranking `json:"rankings"`
+	// The full report of every repository, in the order given
+	Reports []*Report `json:"reports"`
+}
+
+// Summary
+// Gets the totals of the collection compared in a Leaderboard
+func (x *Stats) Summary() RepoSummary {
+	churn := 0
+	for _, changes := range x.churn() {
+		churn += changes
+	}
+	// The number of distinct commit authors
+	return RepoSummary{Repo: x.RepoUser + "/" + x.RepoName, Commits: x.numCommits, PRs: x.numPRs,
+		LinesOfCode: x.totalLinesOfCode, Contributors: len(x.commitAttribution), Churn: churn}
+}
+
+// NewLeaderboard
+// Ranks several collections against each other
+//
+// Parameters:
+//   - repos: the collection of every repository to compare
+//   - n: the number of items to keep in each ranking of each repository report, 0 or less to
+//     keep every item
+//
+// Returns pointer to new Leaderboard
+func NewLeaderboard(repos []*Stats, n int) *Leaderboard {
+	leaderboard := &Leaderboard{GeneratedAt: time.Now(), Repos: make([]RepoSummary, 0, len(repos)),
+		Reports: make([]*Report, 0, len(repos))}
+	commits, prs, lines := make(map[string]int), make(map[string]int), make(map[string]int)
+	contributors, churn := make(map[string]int), make(map[string]int)
+	for _, repo := range repos {
+		summary := repo.Summary()
+		leaderboard.Repos = append(leaderboard.Repos, summary)
+		leaderboard.Reports = append(leaderboard.Reports, repo.Report(n))
+		commits[summary.Repo] = summary.Commits
+		prs[summary.Repo] = summary.PRs
+		lines[summary.Repo] = summary.LinesOfCode
+		contributors[summary.Repo] = summary.Contributors
+		churn[summary.Repo] = summary.Churn
+	}
+
+	leaderboard.Rankings = []Ranking{
+		{Key: "commits", Title: "Most Commits", Items: rankedItems(commits, 0)},
+		{Key: "prs", Title: "Most PRs", Items: rankedItems(prs, 0)},
+		{Key: "lines_of_code", Title: "Most Lines of Code", Items: rankedItems(lines, 0)},
+		{Key: "contributors", Title: "Most Contributors", Items: rankedItems(contributors, 0)},
+		{Key: "churn", Title: "Most Churn (line changes)", Items: rankedItems(churn, 0)},
+	}
+	return leaderboard
+}
+
+// OutputResults
+// Outputs every ranking of the leaderboard
+func (l *Leaderboard) OutputResults() {
+	fmt.Fprint(output, "\n\n")
+	repos := make([]string, 0, len(l.Repos))
+	for _, repo := range l.Repos {
+		repos = append(repos, repo.Repo)
+	}
+	OutputWithTitle("Leaderboard For:", Title, strings.Join(repos, ", "), Subtle)
+	fmt.Fprintln(output)
+
+	for _, ranking := range l.Rankings {
+		Output(ranking.Title+":", TitleNoBold)
+		for index, item := range ranking.Items {
+			OutputFrom([]string{strconv.Itoa(index + 1), item.Name, strconv.Itoa(item.Value)},
+				[]Color{Subtle, Highlight, Subtle})
+		}
+		fmt.Fprintln(output)
+	}
+}
+
+// WriteJSON
+// Writes the leaderboard, including every repository report, as indented JSON
+//
+// Parameters:
+//   - w: writer to output the leaderboard to
+//
+// Returns any errors
+func (l *Leaderboard) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l)
+}
+
+// WriteMarkdown
+// Writes the leaderboard as GitHub flavored markdown, followed by every repository report
+//
+// Parameters:
+//   - w: writer to output the leaderboard to
+//
+// Returns any errors
+func (l *Leaderboard) WriteMarkdown(w io.Writer) error {
+	md := &markdownWriter{w: w}
+
+	md.line("# Leaderboard")
+	md.line("")
+	md.line("_Generated " + l.GeneratedAt.Format("2006-01-02 15:04 MST") + "_")
+	md.line("")
+
+	md.heading("Totals")
+	md.row("Repository", "Commits", "PRs", "Lines of Code", "Contributors", "Churn")
+	md.row("---", "---:", "---:", "---:", "---:", "---:")
+	for _, repo := range l.Repos {
+		md.row(repo.Repo, strconv.Itoa(repo.Commits), strconv.Itoa(repo.PRs), strconv.Itoa(repo.LinesOfCode),
+			strconv.Itoa(repo.Contributors), strconv.Itoa(repo.Churn))
+	}
+	md.line("")
+
+	for _, ranking := range l.Rankings {
+		md.heading(ranking.Title)
+		md.rankedTable("Repository", "Value", ranking.Items)
+	}
+	if md.err != nil {
+		return md.err
+	}
+
+	for _, report := range l.Reports {
+		err := report.WriteMarkdown(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV
+// Writes leaderboard.csv with the totals of every repository
+//
+// Parameters:
+//   - dir: directory to write the file to, created if missing
+//
+// Returns any errors
+func (l *Leaderboard) WriteCSV(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return WrapError(err, "WriteCSV", "while creating "+dir)
+	}
+
+	rows := [][]string{{"repo", "commits", "prs", "lines_of_code", "contributors", "churn"}}
+	for _, repo := range l.Repos {
+		rows = append(rows, []string{repo.Repo, strconv.Itoa(repo.Commits), strconv.Itoa(repo.PRs),
+			strconv.Itoa(repo.LinesOfCode), strconv.Itoa(repo.Contributors), strconv.Itoa(repo.Churn)})
+	}
+	return writeCSVFile(filepath.Join(dir, "leaderboard.csv"), rows)
+}
+
+// ReadRepoList
+// Reads repository entries, one per line, ignoring blank lines and lines starting with #. Entries
+// are either owner/repo, see ParseRepo, or the full URL of the repository
+//
+// Parameters:
+//   - r: reader to read the list from
+//
+// Returns array of entries
+func ReadRepoList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, WrapError(err, "ReadRepoList", "while reading")
+	}
+	entries := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, nil
+}
+
+// ParseRepo
+// Parses a single owner/repo entry
+//
+// Parameters:
+//   - entry: the entry to parse
+//   - subgroups: true if the owner may include subgroups, as for GitLab projects, ex:
+//     group/subgroup/project
+//
+// Returns the owner and repo
+func ParseRepo(entry string, subgroups bool) ([2]string, error) {
+	entry = strings.TrimSpace(entry)
+	index := strings.LastIndex(entry, "/")
+	if index <= 0 || index == len(entry)-1 || strings.HasPrefix(entry, "/") || strings.Contains(entry, "//") ||
+		(!subgroups && strings.Count(entry, "/") > 1) {
+		return [2]string{}, WrapError(errors.New("expected owner/repo"), "ParseRepo", entry)
+	}
+	return [2]string{entry[:index], entry[index+1:]}, nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestParseRepo(t *testing.T) {
+	tests := []struct {
+		entry     string
+		subgroups bool
+		want      [2]string
+		wantErr   bool
+	}{
+		{entry: " owner/repo ", want: [2]string{"owner", "repo"}},
+		{entry: "group/sub/project", subgroups: true, want: [2]string{"group/sub", "project"}},
+		{entry: "group/sub/project", wantErr: true},
+		{entry: "repo", subgroups: true, wantErr: true},
+		{entry: "/owner/repo", subgroups: true, wantErr: true},
+		{entry: "owner/repo/", subgroups: true, wantErr: true},
+		{entry: "group//project", subgroups: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.entry, func(t *testing.T) {
+			got, err := ParseRepo(test.entry, test.subgroups)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseRepo() error = %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseRepo() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteMarkdown
+// Writes the report as GitHub flavored markdown, with a table for every ranking
+//
+// Parameters:
+//   - w: writer to output the report to
+//
+// Returns any errors
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	md := &markdownWriter{w: w}
+
+	md.line("# Stats For: " + r.Repo)
+	md.line("")
+	md.line("_Generated " + r.GeneratedAt.Format("2006-01-02 15:04 MST") + "_")
+	md.line("")
+
+	md.heading("Totals")
+	md.row("Total", "Value")
+	md.row("---", "---:")
+	for _, total := range r.Totals {
+		md.row(total.Label, strconv.Itoa(total.Value))
+	}
+	md.line("")
+
+	for _, ranking := range r.Rankings {
+		md.heading(ranking.Title)
+		md.rankedTable("Name", "Value", ranking.Items)
+	}
+
+	// Sections of a repository, every other collection only has totals, rankings and groups
+	if r.Directories != nil {
+		md.heading("Languages (lines of code)")
+		md.rankedTable("Language", "Lines", r.Languages)
+
+		md.heading("Activity")
+		md.row("Month", "Commits", "PRs")
+		md.row("---", "---:", "---:")
+		for _, period := range r.Activity {
+			md.row(period.Period, strconv.Itoa(period.Commits), strconv.Itoa(period.PRs))
+		}
+		md.line("")
+
+		md.heading("Top Hotspots (churn x size)")
+		md.row("#", "File", "Score", "Lines", "Changes", "Authors")
+		md.row("---:", "---", "---:", "---:", "---:", "---:")
+		for index, hotspot := range r.Hotspots {
+			md.row(strconv.Itoa(index+1), md.code(hotspot.Path), fmt.Sprintf("%.2f", hotspot.Score),
+				strconv.Itoa(hotspot.Lines), strconv.Itoa(hotspot.Changes), strconv.Itoa(hotspot.Authors))
+		}
+		md.line("")
+	}
+
+	if r.Graveyard != nil {
+		md.heading("Graveyard (deleted files)")
+		md.line(fmt.Sprintf("**Deleted files:** %d, **lines ever deleted:** %d",
+			r.Graveyard.DeletedFiles, r.Graveyard.LinesDeleted))
+		md.line("")
+		md.rankedTable("File", "Changes", r.Graveyard.Files)
+	}
+
+	if r.Directories != nil {
+		md.heading("Directories")
+		md.row("Directory", "Lines", "% Lines", "Changes", "% Changes", "useState")
+		md.row("---", "---:", "---:", "---:", "---:", "---:")
+		md.directory(r.Directories, r.Directories, 0)
+		md.line("")
+	}
+
+	if len(r.Renames) > 0 {
+		md.heading("Rename History (newest first)")
+		md.row("File", "Previous Paths")
+		md.row("---", "---")
+		for _, rename := range r.Renames {
+			previous := make([]string, 0, len(rename.PreviousPaths))
+			for _, previousPath := range rename.PreviousPaths {
+				previous = append(previous, md.code(previousPath))
+			}
+			md.row(md.code(rename.Path), strings.Join(previous, " &larr; "))
+		}
+		md.line("")
+	}
+
+	if len(r.Teams) > 0 {
+		md.heading("Teams")
+		md.row("Team", "PRs", "% PRs", "Commits", "% Commits", "Lines Changed", "% Lines Changed", "Members")
+		md.row("---", "---:", "---:", "---:", "---:", "---:", "---:", "---")
+		for _, team := range r.Teams {
+			md.row(team.Team, strconv.Itoa(team.PRs), fmt.Sprintf("%.1f%%", team.PRShare*100),
+				strconv.Itoa(team.Commits), fmt.Sprintf("%.1f%%", team.CommitShare*100),
+				strconv.Itoa(team.LinesChanged), fmt.Sprintf("%.1f%%", team.LinesChangedShare*100),
+				strings.Join(team.Members, ", "))
+		}
+		md.line("")
+	}
+
+	for _, group := range r.Groups {
+		md.heading(group.Title)
+		md.row("Total", "Value")
+		md.row("---", "---:")
+		for _, total := range group.Totals {
+			md.row(total.Label, strconv.Itoa(total.Value))
+		}
+		md.line("")
+		for _, ranking := range group.Rankings {
+			md.line("### " + ranking.Title)
+			md.line("")
+			md.rankedTable("Name", "Value", ranking.Items)
+		}
+	}
+
+	if r.Directories != nil {
+		md.heading("Directory Ownership")
+		md.ownershipTable(r.DirectoryOwnership)
+		md.heading("Knowledge Silos (one author owns 90%+ of changes)")
+		md.ownershipTable(r.KnowledgeSilos)
+		md.heading("File Ownership")
+		md.ownershipTable(r.FileOwnership)
+	}
+
+	return md.err
+}
+
+// markdownWriter
+// Writes markdown lines, keeping the first error so callers only check once
+type markdownWriter struct {
+	w   io.Writer
+	err error
+}
+
+// line
+// Writes a single line followed by a newline
+func (md *markdownWriter) line(text string) {
+	if md.err != nil {
+		return
+	}
+	_, md.err = fmt.Fprintln(md.w, text)
+}
+
+// heading
+// Writes a second level heading followed by a blank line
+func (md *markdownWriter) heading(text string) {
+	md.line("## " + text)
+	md.line("")
+}
+
+// row
+// Writes a single table row, escaping pipes inside cells
+func (md *markdownWriter) row(cells ...string) {
+	escaped := make([]string, 0, len(cells))
+	for _, cell := range cells {
+		escaped = append(escaped, strings.ReplaceAll(cell, "|", "\\|"))
+	}
+	md.line("| " + strings.Join(escaped, " | ") + " |")
+}
+
+// code
+// Formats text as inline code
+func (md *markdownWriter) code(text string) string {
+	return "`" + strings.ReplaceAll(text, "`", "'") + "`"
+}
+
+// rankedTable
+// Writes a numbered table of ranked items followed by a blank line
+func (md *markdownWriter) rankedTable(nameHeader string, valueHeader string, items []RankedItem) {
+	md.row("#", nameHeader, valueHeader)
+	md.row("---:", "---", "---:")
+	for index, item := range items {
+		md.row(strconv.Itoa(index+1), item.Name, strconv.Itoa(item.Value))
+	}
+	md.line("")
+}
+
+// ownershipTable
+// Writes a numbered table of ownership entries followed by a blank line
+func (md *markdownWriter) ownershipTable(ownerships []Ownership) {
+	md.row("#", "Path", "Bus Factor", "Top Owner", "Share", "Changes")
+	md.row("---:", "---", "---:", "---", "---:", "---:")
+	for index, ownership := range ownerships {
+		top := ownership.Owners[0]
+		md.row(strconv.Itoa(index+1), md.code(ownership.Path), strconv.Itoa(ownership.BusFactor),
+			top.Author, fmt.Sprintf("%.0f%%", top.Share*100), strconv.Itoa(ownership.Changes))
+	}
+	md.line("")
+}
+
+// directory
+// Writes a table row for node and each of its subdirectories, indenting each level
+func (md *markdownWriter) directory(node *DirectoryNode, root *DirectoryNode, level int) {
+	md.row(strings.Repeat("&nbsp;&nbsp;", level)+md.code(node.Name),
+		strconv.Itoa(node.Lines), fmt.Sprintf("%.1f%%", percentOf(node.Lines, root.Lines)),
+		strconv.Itoa(node.Changes), fmt.Sprintf("%.1f%%", percentOf(node.Changes, root.Changes)),
+		strconv.Itoa(node.UseStates))
+	for _, child := range node.Children {
+		md.directory(child, root, level+1)
+	}
+}
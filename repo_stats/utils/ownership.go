@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// ownershipSiloShare
+// The share of changes above which a single owner is considered to own a directory
+const ownershipSiloShare = 0.9
+
+// OwnerShare
+// The share of changes to a file or directory made by a single commit author
+type OwnerShare struct {
+	// The commit author, see commitAuthor
+	Author string `json:"author"`
+	// The number of line changes (insertion + deletion) made by the author
+	Changes int `json:"changes"`
+	// Changes divided by the total changes to the file or directory, between 0 and 1
+	Share float64 `json:"share"`
+}
+
+// Ownership
+// Who owns what share of the changes to a file or directory
+type Ownership struct {
+	// The path of the file or directory, "." for the whole repository
+	Path string `json:"path"`
+	// True if Path is a directory
+	Directory bool `json:"directory"`
+	// The number of line changes (insertion + deletion) total
+	Changes int `json:"changes"`
+	// Every author who changed the file or directory, largest share first
+	Owners []OwnerShare `json:"owners"`
+	// The smallest number of authors that together own more than 50% of the changes
+	BusFactor int `json:"bus_factor"`
+	// True if a single author owns at least ownershipSiloShare of the changes
+	Concentrated bool `json:"concentrated"`
+}
+
+// FileOwnership
+// Computes ownership of every changed file from fileHistory, leaving out commits made by GitHub
+//
+// Returns array of ownership, most changed file first
+func (x *Stats) FileOwnership() []Ownership {
+	changes := make(map[string]map[string]int)
+	for _, change := range x.fileHistory {
+		if change.Author == mergeAuthor {
+			continue
+		}
+		addOwnerChanges(changes, change.Path, change.Author, change.Changes)
+	}
+	return buildOwnership(changes, false)
+}
+
+// DirectoryOwnership
+// Computes ownership of every directory containing a changed file from fileHistory,
+// including "." for the whole repository, leaving out commits made by GitHub
+//
+// Returns array of ownership, most changed directory first
+func (x *Stats) DirectoryOwnership() []Ownership {
+	changes := make(map[string]map[string]int)
+	for _, change := range x.fileHistory {
+		if change.Author == mergeAuthor {
+			continue
+		}
+		for dir := path.Dir(change.Path); ; dir = path.Dir(dir) {
+			addOwnerChanges(changes, dir, change.Author, change.Changes)
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+	return buildOwnership(changes, true)
+}
+
+// KnowledgeSilos
+// Gets the directories where a single author owns almost every change
+//
+// Returns array of ownership, most changed directory first
+func (x *Stats) KnowledgeSilos() []Ownership {
+	result := make([]Ownership, 0)
+	for _, ownership := range x.DirectoryOwnership() {
+		if ownership.Concentrated {
+			result = append(result, ownership)
+		}
+	}
+	return result
+}
+
+// topOwnership
+// Gets the first n ownership entries, 0 or less to keep every entry
+func topOwnership(ownerships []Ownership, n int) []Ownership {
+	if n > 0 && n < len(ownerships) {
+		return ownerships[:n]
+	}
+	return ownerships
+}
+
+// addOwnerChanges
+// Adds changes by author to the entry for key, creating the entry if needed
+func addOwnerChanges(changes map[string]map[string]int, key string, author string, n int) {
+	if changes[key] == nil {
+		changes[key] = make(map[string]int)
+	}
+	changes[key][author] += n
+}
+
+// buildOwnership
+// Converts a map of path to author changes into sorted ownership entries
+//
+// Parameters:
+//   - changes: map of path to map of author to number of changes
+//   - directory: whether the paths are directories
+//
+// Returns array of ownership, most changed path first
+func buildOwnership(changes map[string]map[string]int, directory bool) []Ownership {
+	result := make([]Ownership, 0, len(changes))
+	for key, authors := range changes {
+		total := 0
+		for _, n := range authors {
+			total += n
+		}
+		if total == 0 {
+			continue
+		}
+
+		owners := make([]OwnerShare, 0, len(authors))
+		for _, item := range rankedItems(authors, 0) {
+			owners = append(owners, OwnerShare{Author: item.Name, Changes: item.Value,
+				Share: float64(item.Value) / float64(total)})
+		}
+
+		result = append(result, Ownership{Path: key, Directory: directory, Changes: total,
+			Owners: owners, BusFactor: busFactor(owners),
+			Concentrated: owners[0].Share >= ownershipSiloShare})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Changes != result[j].Changes {
+			return result[i].Changes > result[j].Changes
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// busFactor
+// Gets the smallest number of owners that together own more than 50% of the changes
+//
+// Parameters:
+//   - owners: array of owner shares, largest share first
+func busFactor(owners []OwnerShare) int {
+	share := 0.0
+	for index, owner := range owners {
+		share += owner.Share
+		if share > 0.5 {
+			return index + 1
+		}
+	}
+	return len(owners)
+}
+
+// printOwnership
+// Prints ownership entries in order with their bus factor and largest owner, prefixed by a number
+//
+// Parameters:
+//   - ownerships: array of ownership, already in order
+func printOwnership(ownerships []Ownership) {
+	for index, ownership := range ownerships {
+		top := ownership.Owners[0]
+		OutputFrom([]string{strconv.Itoa(index + 1), ownership.Path,
+			"bus factor " + strconv.Itoa(ownership.BusFactor),
+			fmt.Sprintf("(%s owns %.0f%% of %d changes)", top.Author, top.Share*100, ownership.Changes)},
+			[]Color{Subtle, Highlight, TitleNoBold, Subtle})
+	}
+	fmt.Fprintln(output)
+}
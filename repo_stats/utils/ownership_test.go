@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+// newMergeStats
+// Creates stats with a file changed by a contributor and by a merge commit made by GitHub
+func newMergeStats() *Stats {
+	stats := NewStats("owner", "repo", []string{}, []string{}, []string{})
+	stats.SetCommits([]interface{}{
+		map[string]interface{}{"commit": map[string]interface{}{"author": map[string]interface{}{"name": mergeAuthor}}},
+		map[string]interface{}{"commit": map[string]interface{}{"author": map[string]interface{}{"name": "Ada"}}},
+	})
+	stats.SetFileHistory([]FileChange{
+		{SHA: "c2", Author: mergeAuthor, Path: "src/a.go", CommitPath: "src/a.go", Status: "modified", Changes: 40},
+		{SHA: "c1", Author: "Ada", Path: "src/a.go", CommitPath: "src/a.go", Status: "added", Changes: 10},
+	})
+	return stats
+}
+
+func TestOwnershipLeavesOutMergeAuthor(t *testing.T) {
+	stats := newMergeStats()
+	for name, ownerships := range map[string][]Ownership{
+		"FileOwnership":      stats.FileOwnership(),
+		"DirectoryOwnership": stats.DirectoryOwnership(),
+	} {
+		for _, ownership := range ownerships {
+			if len(ownership.Owners) != 1 || ownership.Owners[0].Author != "Ada" || ownership.Changes != 10 {
+				t.Errorf("%s() %s = %+v, want only Ada with 10 changes", name, ownership.Path, ownership)
+			}
+		}
+	}
+}
+
+func TestContributorsLeavesOutMergeAuthor(t *testing.T) {
+	contributors := newMergeStats().Contributors()
+	if len(contributors) != 1 || contributors[0] != (Contributor{Name: "Ada", Commits: 1, LinesChanged: 10}) {
+		t.Errorf("Contributors() = %+v, want only Ada", contributors)
+	}
+}
+
+func TestHotspotsLeavesOutMergeAuthor(t *testing.T) {
+	stats := newMergeStats()
+	stats.SetFileSizes(map[string]int{"src/a.go": 100})
+	stats.SetFileChanges(map[string]int{"src/a.go": 50})
+	hotspots := stats.Hotspots()
+	if len(hotspots) != 1 || hotspots[0].Authors != 1 {
+		t.Errorf("Hotspots() = %+v, want src/a.go with 1 author", hotspots)
+	}
+}
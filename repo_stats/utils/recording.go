@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// transport
+// The transport every request is sent with, nil for http.DefaultTransport
+var transport http.RoundTripper
+
+// SetTransport
+// Sets the transport every request is sent with, such as a RecordingTransport
+//
+// Parameters:
+//   - rt: transport to send requests with, nil for http.DefaultTransport
+func SetTransport(rt http.RoundTripper) {
+	transport = rt
+}
+
+// recordedResponse
+// A single response saved by a RecordingTransport
+type recordedResponse struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+	// "base64" if the body is not valid UTF-8, such as an image, empty otherwise
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// RecordingTransport
+// Sends requests with another transport, saving every response to a directory so a
+// ReplayTransport can serve them back later. Request headers, such as the token, are not saved,
+// and neither are tokens in responses, such as GitHub App installation tokens, see redactTokens
+type RecordingTransport struct {
+	dir    string
+	base   http.RoundTripper
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+// ReplayTransport
+// Serves the responses saved by a RecordingTransport without using the network. A request made
+// several times gets each saved response in order, then the last one again
+type ReplayTransport struct {
+	dir    string
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+// NewRecordingTransport
+// Creates a new RecordingTransport
+//
+// Parameters:
+//   - dir: directory to save responses to, created if missing
+//   - base: transport to send requests with, nil for http.DefaultTransport
+//
+// Returns pointer to new RecordingTransport
+func NewRecordingTransport(dir string, base http.RoundTripper) (*RecordingTransport, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, WrapError(err, "NewRecordingTransport", "while creating "+dir)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RecordingTransport{dir: dir, base: base, counts: make(map[string]int)}, nil
+}
+
+// RoundTrip
+// Sends a request and saves its response
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, WrapError(err, "RoundTrip", "while reading response")
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	saved := redactTokens(body)
+	recorded := recordedResponse{Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode,
+		Header: resp.Header, Body: string(saved)}
+	if !utf8.Valid(saved) {
+		recorded.Body = base64.StdEncoding.EncodeToString(saved)
+		recorded.Encoding = "base64"
+	}
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return nil, WrapError(err, "RoundTrip", "while encoding response")
+	}
+
+	key := recordingKey(req)
+	t.mutex.Lock()
+	index := t.counts[key]
+	t.counts[key]++
+	t.mutex.Unlock()
+	err = os.WriteFile(recordingPath(t.dir, key, index), data, 0644)
+	if err != nil {
+		return nil, WrapError(err, "RoundTrip", "while saving response")
+	}
+	return resp, nil
+}
+
+// redactedToken
+// Replaces the tokens in saved responses
+const redactedToken = "REDACTED"
+
+// redactTokens
+// Replaces the token field of a JSON object response, such as the installation token created by
+// POST /app/installations/{id}/access_tokens, so recordings never hold a usable token
+//
+// Parameters:
+//   - body: the response body
+//
+// Returns the body to save, unchanged if it holds no token
+func redactTokens(body []byte) []byte {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(body, &object) != nil {
+		return body
+	}
+	if _, ok := object["token"]; !ok {
+		return body
+	}
+	object["token"], _ = json.Marshal(redactedToken)
+	redacted, err := json.Marshal(object)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// NewReplayTransport
+// Creates a new ReplayTransport
+//
+// Parameters:
+//   - dir: directory responses were saved to by a RecordingTransport
+//
+// Returns pointer to new ReplayTransport
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, WrapError(err, "NewReplayTransport", "while opening "+dir)
+	}
+	if !info.IsDir() {
+		return nil, WrapError(errors.New("not a directory"), "NewReplayTransport", dir)
+	}
+	return &ReplayTransport{dir: dir, counts: make(map[string]int)}, nil
+}
+
+// RoundTrip
+// Serves the saved response of a request, an error if the request was never recorded
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := recordingKey(req)
+	t.mutex.Lock()
+	index := t.counts[key]
+	data, err := os.ReadFile(recordingPath(t.dir, key, index))
+	if errors.Is(err, os.ErrNotExist) && index > 0 {
+		// Requests made more often than when recording get the last response
+		data, err = os.ReadFile(recordingPath(t.dir, key, index-1))
+	} else if err == nil {
+		t.counts[key]++
+	}
+	t.mutex.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, WrapError(errors.New("no recorded response"), "RoundTrip", req.Method+" "+req.URL.String())
+	}
+	if err != nil {
+		return nil, WrapError(err, "RoundTrip", "while reading recorded response")
+	}
+
+	var recorded recordedResponse
+	err = json.Unmarshal(data, &recorded)
+	if err != nil {
+		return nil, WrapError(err, "RoundTrip", "while parsing recorded response")
+	}
+	body := []byte(recorded.Body)
+	if recorded.Encoding == "base64" {
+		body, err = base64.StdEncoding.DecodeString(recorded.Body)
+		if err != nil {
+			return nil, WrapError(err, "RoundTrip", "while decoding recorded response")
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// recordingKey
+// Identifies a request by its method and URL
+func recordingKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// recordingPath
+// Gets the file of the index-th response to a request
+func recordingPath(dir string, key string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", key, index))
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RenameHistory
+// The previous paths of a file which has been renamed
+type RenameHistory struct {
+	// The current path of the file
+	Path string `json:"path"`
+	// Every previous path of the file, most recent first
+	PreviousPaths []string `json:"previous_paths"`
+}
+
+// SetShowRenames
+// Sets whether the rename history of files is output
+func (x *Stats) SetShowRenames(showRenames bool) {
+	x.showRenames = showRenames
+}
+
+// RenameHistories
+// Gets the rename history of every renamed file from fileHistory
+//
+// Returns array of rename histories, ordered by path
+func (x *Stats) RenameHistories() []RenameHistory {
+	previousPaths := make(map[string][]string)
+	for _, change := range x.fileHistory {
+		if change.Status == "renamed" && change.PreviousPath != "" {
+			previousPaths[change.Path] = append(previousPaths[change.Path], change.PreviousPath)
+		}
+	}
+
+	result := make([]RenameHistory, 0, len(previousPaths))
+	for file, paths := range previousPaths {
+		result = append(result, RenameHistory{Path: file, PreviousPaths: paths})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// printRenames
+// Prints each renamed file followed by its previous paths
+//
+// Parameters:
+//   - renames: array of rename histories
+func printRenames(renames []RenameHistory) {
+	for _, rename := range renames {
+		OutputFrom([]string{rename.Path, "<- " + strings.Join(rename.PreviousPaths, " <- ")},
+			[]Color{Highlight, Subtle})
+	}
+	fmt.Fprintln(output)
+}
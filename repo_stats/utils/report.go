@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Report
+// A structured form of the results of a statistics collection, used for non-text output
+type Report struct {
+	// The repository the report was generated for, as owner/name
+	Repo string `json:"repo"`
+	// The time the report was generated
+	GeneratedAt time.Time `json:"generated_at"`
+	// Repository wide totals, in display order
+	Totals []ReportTotal `json:"totals"`
+	// Top n rankings, in display order
+	Rankings []Ranking `json:"rankings"`
+	// Commits and PRs per month, oldest first
+	Activity []ActivityPeriod `json:"activity"`
+	// Lines of code per language, most lines first
+	Languages []RankedItem `json:"languages"`
+	// Top n hotspots, highest score first
+	Hotspots []Hotspot `json:"hotspots"`
+	// The top n most changed deleted files
+	Graveyard *Graveyard `json:"graveyard"`
+	// Lines, changes and useState calls rolled up per directory
+	Directories *DirectoryNode `json:"directories"`
+	// Previous paths of every renamed file, only set when renames are shown
+	Renames []RenameHistory `json:"renames,omitempty"`
+	// PRs, commits and lines changed per team, only set when teams are set
+	Teams []TeamStats `json:"teams,omitempty"`
+	// Totals and rankings of each part of the collection, such as each channel of a Slack export
+	Groups []ReportGroup `json:"groups,omitempty"`
+	// Ownership of the top n most changed files
+	FileOwnership []Ownership `json:"file_ownership"`
+	// Ownership of the top n most changed directories
+	DirectoryOwnership []Ownership `json:"directory_ownership"`
+	// The top n most changed directories where a single author owns almost every change
+	KnowledgeSilos []Ownership `json:"knowledge_silos"`
+}
+
+// ReportTotal
+// A single repository wide total
+type ReportTotal struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Value int    `json:"value"`
+}
+
+// ReportGroup
+// The totals and rankings of a single part of a collection
+type ReportGroup struct {
+	Title    string        `json:"title"`
+	Totals   []ReportTotal `json:"totals"`
+	Rankings []Ranking     `json:"rankings"`
+}
+
+// Ranking
+// An ordered list of items ranked by an integer value
+type Ranking struct {
+	Key   string       `json:"key"`
+	Title string       `json:"title"`
+	Items []RankedItem `json:"items"`
+}
+
+// RankedItem
+// A single entry of a Ranking
+type RankedItem struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// Report
+// Builds a structured report from the collected statistics
+//
+// Parameters:
+//   - n: the number of items to keep in each ranking, 0 or less to keep every item
+//
+// Returns pointer to new Report
+func (x *Stats) Report(n int) *Report {
+	hotspots := x.Hotspots()
+	if n > 0 && n < len(hotspots) {
+		hotspots = hotspots[:n]
+	}
+
+	var renames []RenameHistory
+	if x.showRenames {
+		renames = x.RenameHistories()
+	}
+
+	var teams []TeamStats
+	if len(x.teams) > 0 {
+		teams = x.Teams()
+	}
+
+	return &Report{
+		Repo:        x.RepoUser + "/" + x.RepoName,
+		GeneratedAt: time.Now(),
+		Totals: []ReportTotal{
+			{Key: "lines_of_code", Label: "Lines of code", Value: x.totalLinesOfCode},
+			{Key: "commits", Label: "Total commits", Value: x.numCommits},
+			{Key: "prs", Label: "Total PRs", Value: x.numPRs},
+			{Key: "use_states", Label: "Total useState calls", Value: x.numUseStates},
+		},
+		Rankings: append([]Ranking{
+			{Key: "prs", Title: "Top PRs", Items: rankedItems(x.prAttribution, n)},
+			{Key: "commits", Title: "Top Commits", Items: rankedItems(x.commitAttribution, n)},
+			{Key: "file_sizes", Title: "Top File Sizes (lines of code)",
+				Items: rankedItems(x.filterFiles(x.fileSizes), n)},
+			{Key: "file_changes", Title: "Top File Changes",
+				Items: rankedItems(x.churn(), n)},
+			{Key: "use_states", Title: "Top Use States",
+				Items: rankedItems(x.filterFiles(x.allUseStates), n)},
+		}, x.teamRankings()...),
+		Activity:           x.Activity(),
+		Languages:          x.Languages(),
+		Hotspots:           hotspots,
+		Graveyard:          x.Graveyard(n),
+		Directories:        x.DirectoryRollup(),
+		Renames:            renames,
+		Teams:              teams,
+		FileOwnership:      topOwnership(x.FileOwnership(), n),
+		DirectoryOwnership: topOwnership(x.DirectoryOwnership(), n),
+		KnowledgeSilos:     topOwnership(x.KnowledgeSilos(), n),
+	}
+}
+
+// WriteJSON
+// Writes the report as indented JSON
+//
+// Parameters:
+//   - w: writer to output the report to
+//
+// Returns any errors
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// OutputResults
+// Outputs the totals and rankings of the report, followed by those of each group, used for
+// collections without a Stats of their own
+func (r *Report) OutputResults() {
+	fmt.Fprint(output, "\n\n")
+	OutputWithTitle("Stats For:", Title, r.Repo, Subtle)
+	fmt.Fprintln(output)
+	printTotalsAndRankings(r.Totals, r.Rankings)
+
+	for _, group := range r.Groups {
+		OutputWithTitle("Stats For:", Title, group.Title, Subtle)
+		fmt.Fprintln(output)
+		printTotalsAndRankings(group.Totals, group.Rankings)
+	}
+}
+
+// printTotalsAndRankings
+// Prints each total followed by each ranking
+func printTotalsAndRankings(totals []ReportTotal, rankings []Ranking) {
+	for _, total := range totals {
+		OutputFrom([]string{total.Label + ":", strconv.Itoa(total.Value)}, []Color{TitleNoBold, Subtle})
+	}
+	fmt.Fprintln(output)
+
+	for _, ranking := range rankings {
+		Output(ranking.Title+":", TitleNoBold)
+		for index, item := range ranking.Items {
+			OutputFrom([]string{strconv.Itoa(index + 1), item.Name, strconv.Itoa(item.Value)},
+				[]Color{Subtle, Highlight, Subtle})
+		}
+		fmt.Fprintln(output)
+	}
+}
+
+// rankedItems
+// Sorts the items of a map by their integer value, breaking ties by name
+//
+// Parameters:
+//   - items: map of strings to integers
+//   - n: the number of items to keep, 0 or less to keep every item
+//
+// Returns array of ranked items, highest value first
+func rankedItems(items map[string]int, n int) []RankedItem {
+	result := make([]RankedItem, 0, len(items))
+	for name, value := range items {
+		result = append(result, RankedItem{Name: name, Value: value})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Value != result[j].Value {
+			return result[i].Value > result[j].Value
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if n > 0 && n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
@@ -22,7 +22,8 @@ import (
 func makeRequest(method string, url string, body string, headers map[string]string) (*http.Response, error) {
 	// Create client
 	client := &http.Client{
-		Timeout: time.Second * 10,
+		Timeout:   time.Second * 10,
+		Transport: transport,
 	}
 
 	// Create request
@@ -46,8 +47,8 @@ func makeRequest(method string, url string, body string, headers map[string]stri
 		return nil, WrapError(err, "makeRequest", "while sending request")
 	}
 
-	// Handle codes
-	if resp.StatusCode != http.StatusOK {
+	// Handle codes, any success such as 201 Created is accepted
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return nil, WrapError(errors.New(resp.Status), "get", "status code error")
 	}
 
@@ -81,6 +82,30 @@ func Get(url string, body string, headers map[string]string) (string, http.Heade
 	return respBodyString, respHeader, nil
 }
 
+// Post
+// Makes post request
+//
+// Parameters:
+//   - url: url to make request to
+//   - body: body content to send, use empty string for no body
+//   - headers: map of header key value pairs to send with request
+//
+// Returns response body, response headers and any errors
+func Post(url string, body string, headers map[string]string) (string, http.Header, error) {
+	resp, err := makeRequest("POST", url, body, headers)
+	if err != nil {
+		return "", nil, WrapError(err, "post", "while calling makeRequest")
+	}
+
+	// Read response
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, WrapError(err, "post", "while reading response")
+	}
+	return string(respBody), resp.Header, nil
+}
+
 // ParseBody
 // Parses response body to create JSON interface
 //
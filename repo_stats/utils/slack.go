@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"regexp"
+	"time"
+)
+
+// slackMentionRegex
+// Matches user mentions in message text, ex: <@U012AB3CD> or <@U012AB3CD|alice>
+var slackMentionRegex = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// slackMessageSubtypes
+// Subtypes of messages written by a user, every other subtype is an event such as a channel join
+var slackMessageSubtypes = map[string]bool{"": true, "thread_broadcast": true, "file_share": true,
+	"me_message": true}
+
+// SlackMessage
+// A single message of a Slack export
+type SlackMessage struct {
+	User      string          `json:"user"`
+	Subtype   string          `json:"subtype"`
+	Text      string          `json:"text"`
+	TS        string          `json:"ts"`
+	ThreadTS  string          `json:"thread_ts"`
+	Files     []interface{}   `json:"files"`
+	Reactions []SlackReaction `json:"reactions"`
+}
+
+// SlackReaction
+// A single emoji reaction to a message, along with who reacted
+type SlackReaction struct {
+	Name string `json:"name"`
+	// The users who reacted, exports may only list some of them
+	Users []string `json:"users"`
+	Count int      `json:"count"`
+}
+
+// SlackChannel
+// The messages of a single channel of a Slack export
+type SlackChannel struct {
+	Name string
+	// Map of day, as YYYY-MM-DD, to the messages posted that day
+	Days map[string][]SlackMessage
+}
+
+// SlackStats
+// Statistics of a Slack workspace export, overall and per channel
+type SlackStats struct {
+	// The name of the workspace, used in place of a repository
+	Workspace string
+	users     map[string]string
+	channels  []SlackChannel
+}
+
+// slackCounts
+// The per user and per day counts of a set of messages
+type slackCounts struct {
+	messages          map[string]int
+	mentionsReceived  map[string]int
+	reactionsGiven    map[string]int
+	reactionsReceived map[string]int
+	filesShared       map[string]int
+	threadReplies     map[string]int
+	days              map[string]int
+}
+
+// NewSlackStats
+// Creates a new SlackStats
+//
+// Parameters:
+//   - workspace: the name of the workspace, used as the title of every output
+//
+// Returns pointer to new SlackStats
+func NewSlackStats(workspace string) *SlackStats {
+	return &SlackStats{Workspace: workspace, users: make(map[string]string), channels: []SlackChannel{}}
+}
+
+// SetUsers
+// Sets the names users are displayed by
+//
+// Parameters:
+//   - users: map of user ID to display name, users not in the map are displayed by ID
+func (x *SlackStats) SetUsers(users map[string]string) {
+	x.users = users
+}
+
+// SetChannels
+// Sets the channels to collect statistics from
+//
+// Parameters:
+//   - channels: array of every channel, in display order
+func (x *SlackStats) SetChannels(channels []SlackChannel) {
+	x.channels = channels
+}
+
+// Report
+// Builds a structured report of the whole workspace, with a group for every channel, so a Slack
+// export is output the same way as a repository
+//
+// Parameters:
+//   - n: the number of items to keep in each ranking, 0 or less to keep every item
+//
+// Returns pointer to new Report
+func (x *SlackStats) Report(n int) *Report {
+	overall := newSlackCounts()
+	channelMessages := make(map[string]int)
+	groups := make([]ReportGroup, 0, len(x.channels))
+	for _, channel := range x.channels {
+		counts := newSlackCounts()
+		counts.add(channel)
+		overall.add(channel)
+		channelMessages["#"+channel.Name] = counts.total(counts.messages)
+		groups = append(groups, ReportGroup{Title: "#" + channel.Name, Totals: counts.totals(),
+			Rankings: x.rankings(counts, n)})
+	}
+
+	return &Report{
+		Repo:        x.Workspace,
+		GeneratedAt: time.Now(),
+		Totals:      append(overall.totals(), ReportTotal{Key: "channels", Label: "Channels", Value: len(x.channels)}),
+		Rankings: append(x.rankings(overall, n),
+			Ranking{Key: "channels", Title: "Most Active Channels (messages)", Items: rankedItems(channelMessages, n)}),
+		Groups: groups,
+	}
+}
+
+// rankings
+// Gets every ranking of a set of counts, with users displayed by name
+func (x *SlackStats) rankings(counts *slackCounts, n int) []Ranking {
+	return []Ranking{
+		{Key: "messages", Title: "Top Messages", Items: rankedItems(x.byName(counts.messages), n)},
+		{Key: "mentions_received", Title: "Most Mentioned", Items: rankedItems(x.byName(counts.mentionsReceived), n)},
+		{Key: "reactions_given", Title: "Top Reactions Given", Items: rankedItems(x.byName(counts.reactionsGiven), n)},
+		{Key: "reactions_received", Title: "Top Reactions Received",
+			Items: rankedItems(x.byName(counts.reactionsReceived), n)},
+		{Key: "files_shared", Title: "Top Files Shared", Items: rankedItems(x.byName(counts.filesShared), n)},
+		{Key: "thread_replies", Title: "Top Thread Replies", Items: rankedItems(x.byName(counts.threadReplies), n)},
+		{Key: "busiest_days", Title: "Busiest Days (messages)", Items: rankedItems(counts.days, n)},
+	}
+}
+
+// byName
+// Converts a map of user ID to a map of display name, merging users which share a name
+func (x *SlackStats) byName(counts map[string]int) map[string]int {
+	result := make(map[string]int, len(counts))
+	for id, count := range counts {
+		name, ok := x.users[id]
+		if !ok || name == "" {
+			name = id
+		}
+		result[name] += count
+	}
+	return result
+}
+
+// newSlackCounts
+// Creates empty slackCounts
+func newSlackCounts() *slackCounts {
+	return &slackCounts{messages: make(map[string]int), mentionsReceived: make(map[string]int),
+		reactionsGiven: make(map[string]int), reactionsReceived: make(map[string]int),
+		filesShared: make(map[string]int), threadReplies: make(map[string]int), days: make(map[string]int)}
+}
+
+// add
+// Counts every message of a channel written by a user, skipping events such as channel joins
+func (c *slackCounts) add(channel SlackChannel) {
+	for day, messages := range channel.Days {
+		for _, message := range messages {
+			if message.User == "" || !slackMessageSubtypes[message.Subtype] {
+				continue
+			}
+			c.messages[message.User]++
+			c.days[day]++
+			if len(message.Files) > 0 {
+				c.filesShared[message.User] += len(message.Files)
+			}
+			if message.ThreadTS != "" && message.ThreadTS != message.TS {
+				c.threadReplies[message.User]++
+			}
+
+			// A user mentioned several times in one message is mentioned once
+			mentioned := make(map[string]bool)
+			for _, match := range slackMentionRegex.FindAllStringSubmatch(message.Text, -1) {
+				if !mentioned[match[1]] {
+					mentioned[match[1]] = true
+					c.mentionsReceived[match[1]]++
+				}
+			}
+
+			for _, reaction := range message.Reactions {
+				for _, user := range reaction.Users {
+					c.reactionsGiven[user]++
+				}
+				count := reaction.Count
+				if count < len(reaction.Users) {
+					count = len(reaction.Users)
+				}
+				if count > 0 {
+					c.reactionsReceived[message.User] += count
+				}
+			}
+		}
+	}
+}
+
+// total
+// Gets the sum of every count
+func (c *slackCounts) total(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// totals
+// Gets the totals of a set of counts, in display order
+func (c *slackCounts) totals() []ReportTotal {
+	return []ReportTotal{
+		{Key: "messages", Label: "Total messages", Value: c.total(c.messages)},
+		{Key: "thread_replies", Label: "Total thread replies", Value: c.total(c.threadReplies)},
+		{Key: "reactions", Label: "Total reactions", Value: c.total(c.reactionsReceived)},
+		{Key: "files_shared", Label: "Total files shared", Value: c.total(c.filesShared)},
+		{Key: "members", Label: "Active members", Value: len(c.messages)},
+	}
+}
@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion
+// The version of the snapshot file format written by WriteSnapshot
+const SnapshotVersion = 1
+
+// Snapshot
+// The full results of a single run, saved to compare runs against each other
+type Snapshot struct {
+	// The version of the snapshot file format
+	Version int `json:"version"`
+	// The repository, as owner/name
+	Repo string `json:"repo"`
+	// The time the snapshot was taken
+	CreatedAt time.Time `json:"created_at"`
+	// Repository wide totals, in display order
+	Totals []ReportTotal `json:"totals"`
+	// A map of GitHub username to number of PRs authored
+	PRAttribution map[string]int `json:"pr_attribution"`
+	// A map of commit author, by login or else name, to number of commits authored
+	CommitAttribution map[string]int `json:"commit_attribution"`
+	// A map of valid file path to number of lines in the file
+	FileSizes map[string]int `json:"file_sizes"`
+	// A map of valid file path to number of line changes, as used by churn rankings
+	FileChanges map[string]int `json:"file_changes"`
+	// A map of valid file path to number of useState calls in the file
+	UseStates map[string]int `json:"use_states"`
+	// Every hotspot, highest score first
+	Hotspots []Hotspot `json:"hotspots"`
+}
+
+// Snapshot
+// Gets the full results of the collection as a Snapshot
+func (x *Stats) Snapshot() *Snapshot {
+	return &Snapshot{
+		Version:           SnapshotVersion,
+		Repo:              x.RepoUser + "/" + x.RepoName,
+		CreatedAt:         time.Now(),
+		Totals:            x.Report(0).Totals,
+		PRAttribution:     x.prAttribution,
+		CommitAttribution: x.commitAttribution,
+		FileSizes:         x.filterFiles(x.fileSizes),
+		FileChanges:       x.churn(),
+		UseStates:         x.filterFiles(x.allUseStates),
+		Hotspots:          x.Hotspots(),
+	}
+}
+
+// WriteSnapshot
+// Writes the snapshot as JSON to a file
+//
+// Parameters:
+//   - path: the file to write to
+//
+// Returns any errors
+func (s *Snapshot) WriteSnapshot(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return WrapError(err, "WriteSnapshot", "while encoding snapshot")
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return WrapError(err, "WriteSnapshot", "while writing "+path)
+	}
+	return nil
+}
+
+// LoadSnapshot
+// Reads a snapshot written by WriteSnapshot
+//
+// Parameters:
+//   - path: the file to read
+//
+// Returns pointer to the snapshot, or an error if it was written by a newer version
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, WrapError(err, "LoadSnapshot", "while reading "+path)
+	}
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(data, snapshot)
+	if err != nil {
+		return nil, WrapError(err, "LoadSnapshot", "while parsing "+path)
+	}
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, WrapError(errors.New("unsupported snapshot version "+strconv.Itoa(snapshot.Version)),
+			"LoadSnapshot", path)
+	}
+	return snapshot, nil
+}
+
+// TotalDelta
+// The change of a single total between two snapshots
+type TotalDelta struct {
+	Label string `json:"label"`
+	Old   int    `json:"old"`
+	New   int    `json:"new"`
+}
+
+// RankMovement
+// The change of a single item of a ranking between two snapshots, a rank of 0 means unranked
+type RankMovement struct {
+	Name     string `json:"name"`
+	OldRank  int    `json:"old_rank"`
+	NewRank  int    `json:"new_rank"`
+	OldValue int    `json:"old_value"`
+	NewValue int    `json:"new_value"`
+}
+
+// RankingDelta
+// The change of a ranking between two snapshots
+type RankingDelta struct {
+	Title string `json:"title"`
+	// True if items are ranked by position only, and values are not meaningful
+	Positional bool `json:"positional"`
+	// Movement of every item in the new top n
+	Movements []RankMovement `json:"movements"`
+	// Items in the new top n which were not in the old top n
+	Entered []string `json:"entered"`
+	// Items in the old top n which are not in the new top n
+	Left []string `json:"left"`
+}
+
+// SnapshotDiff
+// The differences between an older and a newer snapshot of the same repository
+type SnapshotDiff struct {
+	Old      *Snapshot      `json:"-"`
+	New      *Snapshot      `json:"-"`
+	Totals   []TotalDelta   `json:"totals"`
+	Rankings []RankingDelta `json:"rankings"`
+	// Contributors with commits or PRs in the new snapshot who had none in the old snapshot
+	NewContributors []string `json:"new_contributors"`
+	// Contributors from the old snapshot without any commits or PRs since
+	DepartedContributors []string `json:"departed_contributors"`
+}
+
+// CompareSnapshots
+// Compares an older snapshot against a newer snapshot
+//
+// Parameters:
+//   - older: the older snapshot, such as last year's
+//   - newer: the newer snapshot
+//   - n: the number of items compared in each ranking
+//
+// Returns pointer to the differences, or an error if the snapshots are of different repositories
+func CompareSnapshots(older *Snapshot, newer *Snapshot, n int) (*SnapshotDiff, error) {
+	if !strings.EqualFold(older.Repo, newer.Repo) {
+		return nil, WrapError(errors.New("snapshots are of "+older.Repo+" and "+newer.Repo),
+			"CompareSnapshots", "only snapshots of the same repository can be compared")
+	}
+	diff := &SnapshotDiff{Old: older, New: newer}
+
+	oldTotals := make(map[string]int)
+	for _, total := range older.Totals {
+		oldTotals[total.Key] = total.Value
+	}
+	for _, total := range newer.Totals {
+		diff.Totals = append(diff.Totals, TotalDelta{Label: total.Label, Old: oldTotals[total.Key], New: total.Value})
+	}
+
+	oldHotspots, newHotspots := make(map[string]int), make(map[string]int)
+	for index, hotspot := range older.Hotspots {
+		oldHotspots[hotspot.Path] = len(older.Hotspots) - index
+	}
+	for index, hotspot := range newer.Hotspots {
+		newHotspots[hotspot.Path] = len(newer.Hotspots) - index
+	}
+
+	rankings := []struct {
+		title      string
+		old, new   map[string]int
+		positional bool
+	}{
+		{"Top Commits", older.CommitAttribution, newer.CommitAttribution, false},
+		{"Top PRs", older.PRAttribution, newer.PRAttribution, false},
+		{"Top File Sizes (lines of code)", older.FileSizes, newer.FileSizes, false},
+		{"Top File Changes", older.FileChanges, newer.FileChanges, false},
+		{"Top Use States", older.UseStates, newer.UseStates, false},
+		// Hotspot scores are relative to each snapshot, so hotspots are compared by position
+		{"Top Hotspots (churn x size)", oldHotspots, newHotspots, true},
+	}
+	for _, ranking := range rankings {
+		delta := compareRanking(ranking.title, ranking.old, ranking.new, n)
+		delta.Positional = ranking.positional
+		diff.Rankings = append(diff.Rankings, delta)
+	}
+
+	contributed := func(s *Snapshot, name string) int {
+		return s.CommitAttribution[name] + s.PRAttribution[name]
+	}
+	names := make(map[string]bool)
+	for _, attribution := range []map[string]int{older.CommitAttribution, older.PRAttribution,
+		newer.CommitAttribution, newer.PRAttribution} {
+		for name := range attribution {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		oldCount, newCount := contributed(older, name), contributed(newer, name)
+		if oldCount == 0 && newCount > 0 {
+			diff.NewContributors = append(diff.NewContributors, name)
+		}
+		if oldCount > 0 && newCount <= oldCount {
+			diff.DepartedContributors = append(diff.DepartedContributors, name)
+		}
+	}
+	sort.Strings(diff.NewContributors)
+	sort.Strings(diff.DepartedContributors)
+	return diff, nil
+}
+
+// compareRanking
+// Compares the top n items of a ranking between two snapshots
+func compareRanking(title string, older map[string]int, newer map[string]int, n int) RankingDelta {
+	oldRanks := make(map[string]int)
+	for index, item := range rankedItems(older, 0) {
+		oldRanks[item.Name] = index + 1
+	}
+	newTop := rankedItems(newer, n)
+	newRanks := make(map[string]int)
+	for index, item := range newTop {
+		newRanks[item.Name] = index + 1
+	}
+
+	delta := RankingDelta{Title: title}
+	for index, item := range newTop {
+		delta.Movements = append(delta.Movements, RankMovement{Name: item.Name, OldRank: oldRanks[item.Name],
+			NewRank: index + 1, OldValue: older[item.Name], NewValue: item.Value})
+		if oldRank, ok := oldRanks[item.Name]; !ok || oldRank > n {
+			delta.Entered = append(delta.Entered, item.Name)
+		}
+	}
+	for _, item := range rankedItems(older, n) {
+		if _, ok := newRanks[item.Name]; !ok {
+			delta.Left = append(delta.Left, item.Name)
+		}
+	}
+	return delta
+}
+
+// OutputResults
+// Outputs the differences between two snapshots with arrows for each change
+func (d *SnapshotDiff) OutputResults() {
+	fmt.Fprint(output, "\n\n")
+	OutputWithTitle("Changes For:", Title, d.New.Repo, Subtle)
+	OutputFrom([]string{d.Old.CreatedAt.Format("2006-01-02"), "->", d.New.CreatedAt.Format("2006-01-02")},
+		[]Color{Subtle, Subtle, Subtle})
+	fmt.Fprintln(output)
+
+	for _, total := range d.Totals {
+		arrow, arrowColor := deltaArrow(total.New - total.Old)
+		OutputFrom([]string{total.Label + ":", strconv.Itoa(total.Old), "->", strconv.Itoa(total.New),
+			arrow + " " + formatDelta(total.New-total.Old) + formatPercentDelta(total.Old, total.New)},
+			[]Color{TitleNoBold, Subtle, Subtle, Highlight, arrowColor})
+	}
+	fmt.Fprintln(output)
+
+	for _, ranking := range d.Rankings {
+		Output(ranking.Title+":", TitleNoBold)
+		for _, movement := range ranking.Movements {
+			if ranking.Positional {
+				OutputFrom([]string{strconv.Itoa(movement.NewRank), movement.Name, rankMovement(movement)},
+					[]Color{Subtle, Highlight, rankMovementColor(movement)})
+				continue
+			}
+			OutputFrom([]string{strconv.Itoa(movement.NewRank), movement.Name,
+				strconv.Itoa(movement.OldValue) + " -> " + strconv.Itoa(movement.NewValue),
+				rankMovement(movement)},
+				[]Color{Subtle, Highlight, Subtle, rankMovementColor(movement)})
+		}
+		for _, name := range ranking.Entered {
+			OutputFrom([]string{"  entered", name}, []Color{Success, Subtle})
+		}
+		for _, name := range ranking.Left {
+			OutputFrom([]string{"  left", name}, []Color{Err, Subtle})
+		}
+		fmt.Fprintln(output)
+	}
+
+	Output("New Contributors:", TitleNoBold)
+	for _, name := range d.NewContributors {
+		OutputFrom([]string{"+", name}, []Color{Success, Highlight})
+	}
+	fmt.Fprintln(output)
+	Output("Departed Contributors (no commits or PRs since):", TitleNoBold)
+	for _, name := range d.DepartedContributors {
+		OutputFrom([]string{"-", name}, []Color{Err, Subtle})
+	}
+	fmt.Fprintln(output)
+}
+
+// deltaArrow
+// Gets an arrow and color for the direction of a change
+func deltaArrow(delta int) (string, Color) {
+	switch {
+	case delta > 0:
+		return "▲", Success
+	case delta < 0:
+		return "▼", Err
+	}
+	return "=", Subtle
+}
+
+// formatDelta
+// Formats a change with an explicit sign
+func formatDelta(delta int) string {
+	if delta > 0 {
+		return "+" + strconv.Itoa(delta)
+	}
+	return strconv.Itoa(delta)
+}
+
+// formatPercentDelta
+// Formats a change as a percentage of the old value, empty if the old value is 0
+func formatPercentDelta(old int, new int) string {
+	if old == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.1f%%)", float64(new-old)/float64(old)*100)
+}
+
+// rankMovement
+// Describes how far an item moved in a ranking
+func rankMovement(movement RankMovement) string {
+	switch {
+	case movement.OldRank == 0:
+		return "★ new"
+	case movement.OldRank > movement.NewRank:
+		return "▲ " + strconv.Itoa(movement.OldRank-movement.NewRank)
+	case movement.OldRank < movement.NewRank:
+		return "▼ " + strconv.Itoa(movement.NewRank-movement.OldRank)
+	}
+	return "="
+}
+
+// rankMovementColor
+// Gets the color for how an item moved in a ranking
+func rankMovementColor(movement RankMovement) Color {
+	if movement.OldRank == 0 {
+		return Success
+	}
+	_, arrowColor := deltaArrow(movement.OldRank - movement.NewRank)
+	return arrowColor
+}
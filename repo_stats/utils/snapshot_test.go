@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	older := &Snapshot{Repo: "ctc-uci/project", Totals: []ReportTotal{{Key: "commits", Label: "Commits", Value: 10}}}
+	newer := &Snapshot{Repo: "CTC-UCI/Project", Totals: []ReportTotal{{Key: "commits", Label: "Commits", Value: 25}}}
+
+	diff, err := CompareSnapshots(older, newer, 5)
+	if err != nil {
+		t.Fatalf("CompareSnapshots() error = %v", err)
+	}
+	if want := []TotalDelta{{Label: "Commits", Old: 10, New: 25}}; !reflect.DeepEqual(diff.Totals, want) {
+		t.Errorf("Totals = %+v, want %+v", diff.Totals, want)
+	}
+
+	other := &Snapshot{Repo: "ctc-uci/other", Totals: newer.Totals}
+	if _, err := CompareSnapshots(older, other, 5); err == nil {
+		t.Error("CompareSnapshots() of different repositories error = nil, want an error")
+	}
+}
@@ -5,6 +5,11 @@ import (
 	"strconv"
 )
 
+// mergeAuthor
+// The author name of commits GitHub makes itself, such as merges from the web UI, which are not
+// attributed to any contributor
+const mergeAuthor = "GitHub"
+
 // Stats
 // Represents all statistics pulled from a repo
 type Stats struct {
@@ -25,14 +30,26 @@ type Stats struct {
 	numUseStates int
 	// A map of GitHub username to number of PRs authored
 	prAttribution map[string]int
-	// A map of GitHub username to number of commits authored
+	// A map of commit author to number of commits authored, see commitAuthor
 	commitAttribution map[string]int
+	// A map of commit SHA to the author the commit is attributed to
+	commitAuthors map[string]string
 	// A map of file path to file api url
 	fileURLs map[string]string
 	// A map of file path to number of line changes (insertion + deletion) total
 	fileChanges map[string]int
 	// A map of file path to number of lines in the file
 	fileSizes map[string]int
+	// An array of every change made to a file by a commit
+	fileHistory []FileChange
+	// The number of directory levels kept when rolling up directories
+	directoryDepth int
+	// Whether the rename history of files is output
+	showRenames bool
+	// Whether churn rankings only include files present in the analyzed tree
+	existingOnly bool
+	// A map of team name to the contributors in the team
+	teams map[string][]string
 	// An array of file extensions (.png, .svg, .jpg, etc) to ignore
 	ignoreExtensions []string
 	// An array of file names (yarn.lock, package-lock.json, etc) to ignore
@@ -41,6 +58,29 @@ type Stats struct {
 	ignoreDirs []string
 }
 
+// FileChange
+// Represents the changes made to a single file by a single commit
+type FileChange struct {
+	// The SHA of the commit which made the change
+	SHA string `json:"sha"`
+	// The name of the commit author, replaced by the keys of commitAttribution in Stats
+	Author string `json:"author"`
+	// The current path of the changed file, following any later renames
+	Path string `json:"path"`
+	// The path of the file as of the commit, differs from Path if the file was later renamed
+	CommitPath string `json:"commit_path"`
+	// The path of the file before the commit, only set when Status is "renamed"
+	PreviousPath string `json:"previous_path,omitempty"`
+	// The status of the file in the commit: "added", "modified", "removed", "renamed", etc
+	Status string `json:"status"`
+	// The number of lines inserted
+	Additions int `json:"additions"`
+	// The number of lines deleted
+	Deletions int `json:"deletions"`
+	// The number of line changes (insertion + deletion)
+	Changes int `json:"changes"`
+}
+
 // NewStats
 // Returns a new Stats struct with proper attributes
 //
@@ -58,7 +98,9 @@ func NewStats(repoUser string, repoName string,
 		numPRs: 0, numCommits: 0, totalLinesOfCode: 0,
 		allPRs: make([]interface{}, 0), allCommits: make([]interface{}, 0),
 		prAttribution: make(map[string]int), commitAttribution: make(map[string]int),
-		fileURLs: make(map[string]string), fileChanges: make(map[string]int), fileSizes: make(map[string]int),
+		commitAuthors: make(map[string]string),
+		fileURLs:      make(map[string]string), fileChanges: make(map[string]int), fileSizes: make(map[string]int),
+		fileHistory: make([]FileChange, 0), directoryDepth: 2,
 		ignoreExtensions: ignoreExtensions, ignoreFiles: ignoreFiles, ignoreDirs: ignoreDirs}
 }
 
@@ -89,14 +131,45 @@ func (x *Stats) SetCommits(commits []interface{}) {
 	x.numCommits = len(commits)
 	x.allCommits = commits
 	for _, commit := range commits {
-		_commit := commit.(map[string]interface{})["commit"]
-		_committer := _commit.(map[string]interface{})["author"]
-		_name := _committer.(map[string]interface{})["name"]
-		if _name != "GitHub" {
-			attribution := _name.(string)
+		attribution := commitAuthor(commit)
+		if sha, ok := commit.(map[string]interface{})["sha"].(string); ok {
+			x.commitAuthors[sha] = attribution
+		}
+		if attribution != mergeAuthor {
 			x.commitAttribution[attribution]++
 		}
 	}
+	for index := range x.fileHistory {
+		x.fileHistory[index].Author = x.changeAuthor(x.fileHistory[index])
+	}
+}
+
+// commitAuthor
+// Gets the author a commit is attributed to: the GitHub login of the author, the same as PRs are
+// attributed by, or the author name if the author has no GitHub account. Commits made by GitHub
+// are attributed to mergeAuthor
+func commitAuthor(commit interface{}) string {
+	_commit := commit.(map[string]interface{})["commit"]
+	_committer := _commit.(map[string]interface{})["author"]
+	_name, _ := _committer.(map[string]interface{})["name"].(string)
+	if _name == mergeAuthor {
+		return _name
+	}
+	if _author, ok := commit.(map[string]interface{})["author"].(map[string]interface{}); ok {
+		if _login, _ := _author["login"].(string); _login != "" {
+			return _login
+		}
+	}
+	return _name
+}
+
+// changeAuthor
+// Gets the author a file change is attributed to, the same as its commit
+func (x *Stats) changeAuthor(change FileChange) string {
+	if author, ok := x.commitAuthors[change.SHA]; ok {
+		return author
+	}
+	return change.Author
 }
 
 // SetFileUrls
@@ -124,6 +197,19 @@ func (x *Stats) SetFileChanges(fileChanges map[string]int) {
 	x.fileChanges = filteredFiles
 }
 
+// SetFileHistory
+// Sets the local fileHistory to fileHistory, only includes valid files
+// fileHistory should be ordered newest commit first
+func (x *Stats) SetFileHistory(fileHistory []FileChange) {
+	x.fileHistory = make([]FileChange, 0, len(fileHistory))
+	for _, change := range fileHistory {
+		if x.isValidFile(change.Path) {
+			change.Author = x.changeAuthor(change)
+			x.fileHistory = append(x.fileHistory, change)
+		}
+	}
+}
+
 // SetUseStates
 // Sets the local allUseStates to allUseStates and set numUseStates
 func (x *Stats) SetUseStates(allUseStates map[string]int) {
@@ -158,7 +244,7 @@ func (x *Stats) TopFileSizes(n int) map[string]int {
 // TopFileChanges
 // Gets the top n file changes (in order)
 func (x *Stats) TopFileChanges(n int) map[string]int {
-	result := x.filterFiles(x.fileChanges)
+	result := x.churn()
 	result = topnMapStrInt(result, n)
 	return result
 }
@@ -180,11 +266,11 @@ func (x *Stats) TotalLinesOfCode() int {
 // OutputResults
 // Outputs results of a statistics collection
 func (x *Stats) OutputResults() {
-	fmt.Println("\n")
+	fmt.Fprint(output, "\n\n")
 
 	OutputWithTitle("Stats For:", Title,
 		x.RepoUser+"/"+x.RepoName, Subtle)
-	fmt.Println()
+	fmt.Fprintln(output)
 
 	OutputFrom([]string{"Lines of code:", strconv.Itoa(x.totalLinesOfCode)},
 		[]Color{TitleNoBold, Subtle})
@@ -194,7 +280,7 @@ func (x *Stats) OutputResults() {
 		[]Color{TitleNoBold, Subtle})
 	OutputFrom([]string{"Total useState calls:", strconv.Itoa(x.numUseStates)},
 		[]Color{TitleNoBold, Subtle})
-	fmt.Println()
+	fmt.Fprintln(output)
 
 	Output("Top PRs:", TitleNoBold)
 	printTop(x.TopPRs(5))
@@ -206,6 +292,24 @@ func (x *Stats) OutputResults() {
 	printTop(x.TopFileChanges(5))
 	Output("Top Use States:", TitleNoBold)
 	printTop(x.TopUseStates(5))
+	Output("Top Hotspots (churn x size):", TitleNoBold)
+	printHotspots(x.TopHotspots(5))
+	Output("Graveyard (deleted files):", TitleNoBold)
+	printGraveyard(x.Graveyard(5))
+	Output("Directories (lines, changes, useState):", TitleNoBold)
+	printDirectories(x.DirectoryRollup())
+	if x.showRenames {
+		Output("Rename History (newest first):", TitleNoBold)
+		printRenames(x.RenameHistories())
+	}
+	if len(x.teams) > 0 {
+		Output("Teams (share of totals):", TitleNoBold)
+		printTeams(x.Teams())
+	}
+	Output("Directory Ownership:", TitleNoBold)
+	printOwnership(topOwnership(x.DirectoryOwnership(), 5))
+	Output("Knowledge Silos (one author owns 90%+ of changes):", TitleNoBold)
+	printOwnership(topOwnership(x.KnowledgeSilos(), 5))
 }
 
 func (x *Stats) Files() map[string]int {
@@ -67,19 +67,7 @@ func (x *Stats) isInsideDirectory(filePath string) (bool, error) {
 func (x *Stats) filterFiles(fileMap map[string]int) map[string]int {
 	result := make(map[string]int)
 	for file, _ := range fileMap {
-		if slices.Contains(x.ignoreFiles, filepath.Base(file)) {
-			continue
-		}
-
-		if slices.Contains(x.ignoreExtensions, filepath.Ext(file)) {
-			continue
-		}
-
-		ignoreDir, err := x.isInsideDirectory(file)
-		if err != nil {
-			continue
-		}
-		if ignoreDir {
+		if !x.isValidFile(file) {
 			continue
 		}
 
@@ -88,6 +76,29 @@ func (x *Stats) filterFiles(fileMap map[string]int) map[string]int {
 	return result
 }
 
+// isValidFile
+// Checks a single file path against the filepath and file extension filtering rules
+//
+// Parameters:
+//   - file: path of the file to check
+//
+// Returns: true if the file should be included in statistics
+func (x *Stats) isValidFile(file string) bool {
+	if slices.Contains(x.ignoreFiles, filepath.Base(file)) {
+		return false
+	}
+
+	if slices.Contains(x.ignoreExtensions, filepath.Ext(file)) {
+		return false
+	}
+
+	ignoreDir, err := x.isInsideDirectory(file)
+	if err != nil {
+		return false
+	}
+	return !ignoreDir
+}
+
 // printTop
 // Prints the items in order of an integer value, prefixed by a number
 //
@@ -108,5 +119,5 @@ func printTop(items map[string]int) {
 		OutputFrom([]string{strconv.Itoa(index + 1), key, strconv.Itoa(items[key])},
 			[]Color{Subtle, Highlight, Subtle})
 	}
-	fmt.Println()
+	fmt.Fprintln(output)
 }
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unassignedTeam
+// The team of every contributor not assigned to a team
+const unassignedTeam = "Unassigned"
+
+// TeamStats
+// The PRs, commits and lines changed of every member of a team, and their share of the
+// repository wide totals
+type TeamStats struct {
+	Team string `json:"team"`
+	// Members with at least one PR, commit or line changed, in alphabetical order
+	Members      []string `json:"members"`
+	PRs          int      `json:"prs"`
+	Commits      int      `json:"commits"`
+	LinesChanged int      `json:"lines_changed"`
+	// Shares of the repository wide totals, from 0 to 1
+	PRShare           float64 `json:"pr_share"`
+	CommitShare       float64 `json:"commit_share"`
+	LinesChangedShare float64 `json:"lines_changed_share"`
+}
+
+// LoadTeams
+// Reads a JSON mapping of team name to the contributors in the team, ex:
+// {"frontend": ["alice", "Alice Q"], "backend": ["bob"]}. Contributors are matched by GitHub
+// login, or author name for commits by authors without a GitHub account, ignoring case, so a
+// member may be listed by both. A contributor may be in several teams
+//
+// Parameters:
+//   - path: the file to read
+//
+// Returns map of team name to members
+func LoadTeams(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, WrapError(err, "LoadTeams", "while reading "+path)
+	}
+	teams := make(map[string][]string)
+	err = json.Unmarshal(data, &teams)
+	if err != nil {
+		return nil, WrapError(err, "LoadTeams", "while parsing "+path)
+	}
+	for team := range teams {
+		if strings.TrimSpace(team) == "" || team == unassignedTeam {
+			return nil, WrapError(errors.New("invalid team name \""+team+"\""), "LoadTeams", path)
+		}
+	}
+	return teams, nil
+}
+
+// SetTeams
+// Sets the teams contributors are grouped into, every ranking of contributors is then also
+// aggregated per team
+//
+// Parameters:
+//   - teams: map of team name to members, as read by LoadTeams, nil for no teams
+func (x *Stats) SetTeams(teams map[string][]string) {
+	x.teams = teams
+}
+
+// Teams
+// Aggregates the PRs, commits and lines changed of contributors per team. Contributors not in
+// any team are grouped into the "Unassigned" team
+//
+// Returns array of every team with activity, most commits first, empty if no teams are set
+func (x *Stats) Teams() []TeamStats {
+	if len(x.teams) == 0 {
+		return []TeamStats{}
+	}
+
+	memberTeams := make(map[string][]string)
+	for team, members := range x.teams {
+		for _, member := range members {
+			key := strings.ToLower(strings.TrimSpace(member))
+			memberTeams[key] = append(memberTeams[key], team)
+		}
+	}
+
+	teams := make(map[string]*TeamStats)
+	members := make(map[string]map[string]bool)
+	add := func(attribution map[string]int, field func(team *TeamStats) *int) int {
+		total := 0
+		for name, value := range attribution {
+			total += value
+			memberOf, ok := memberTeams[strings.ToLower(name)]
+			if !ok {
+				memberOf = []string{unassignedTeam}
+			}
+			for _, teamName := range memberOf {
+				if teams[teamName] == nil {
+					teams[teamName] = &TeamStats{Team: teamName}
+					members[teamName] = make(map[string]bool)
+				}
+				*field(teams[teamName]) += value
+				members[teamName][name] = true
+			}
+		}
+		return total
+	}
+	totalPRs := add(x.prAttribution, func(team *TeamStats) *int { return &team.PRs })
+	totalCommits := add(x.commitAttribution, func(team *TeamStats) *int { return &team.Commits })
+	totalLines := add(x.linesChangedAttribution(), func(team *TeamStats) *int { return &team.LinesChanged })
+
+	result := make([]TeamStats, 0, len(teams))
+	for name, team := range teams {
+		for member := range members[name] {
+			team.Members = append(team.Members, member)
+		}
+		sort.Strings(team.Members)
+		team.PRShare = percentOf(team.PRs, totalPRs) / 100
+		team.CommitShare = percentOf(team.Commits, totalCommits) / 100
+		team.LinesChangedShare = percentOf(team.LinesChanged, totalLines) / 100
+		result = append(result, *team)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Commits != result[j].Commits {
+			return result[i].Commits > result[j].Commits
+		}
+		return result[i].Team < result[j].Team
+	})
+	return result
+}
+
+// teamRankings
+// Gets the team aggregate of every ranking of contributors, empty if no teams are set
+func (x *Stats) teamRankings() []Ranking {
+	teams := x.Teams()
+	if len(teams) == 0 {
+		return []Ranking{}
+	}
+	prs, commits, lines := make(map[string]int), make(map[string]int), make(map[string]int)
+	for _, team := range teams {
+		prs[team.Team] = team.PRs
+		commits[team.Team] = team.Commits
+		lines[team.Team] = team.LinesChanged
+	}
+	return []Ranking{
+		{Key: "team_prs", Title: "Team PRs", Items: rankedItems(prs, 0)},
+		{Key: "team_commits", Title: "Team Commits", Items: rankedItems(commits, 0)},
+		{Key: "team_lines_changed", Title: "Team Lines Changed", Items: rankedItems(lines, 0)},
+	}
+}
+
+// linesChangedAttribution
+// Gets a map of commit author to the number of line changes to valid files, leaving out
+// commits made by GitHub the same way as commitAttribution
+func (x *Stats) linesChangedAttribution() map[string]int {
+	lines := make(map[string]int)
+	for _, change := range x.fileHistory {
+		if change.Author != "" && change.Author != mergeAuthor {
+			lines[change.Author] += change.Changes
+		}
+	}
+	return lines
+}
+
+// printTeams
+// Prints a ranking of teams by PRs, commits and lines changed, with each team's share
+func printTeams(teams []TeamStats) {
+	rankings := []struct {
+		title string
+		value func(team TeamStats) (int, float64)
+	}{
+		{"PRs", func(team TeamStats) (int, float64) { return team.PRs, team.PRShare }},
+		{"Commits", func(team TeamStats) (int, float64) { return team.Commits, team.CommitShare }},
+		{"Lines Changed", func(team TeamStats) (int, float64) { return team.LinesChanged, team.LinesChangedShare }},
+	}
+	for _, ranking := range rankings {
+		sorted := append([]TeamStats{}, teams...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, _ := ranking.value(sorted[i])
+			b, _ := ranking.value(sorted[j])
+			return a > b
+		})
+		Output("  "+ranking.title+":", TitleNoBold)
+		for index, team := range sorted {
+			value, share := ranking.value(team)
+			OutputFrom([]string{strconv.Itoa(index + 1), team.Team, strconv.Itoa(value),
+				fmt.Sprintf("(%.1f%%)", share*100)},
+				[]Color{Subtle, Highlight, Subtle, Subtle})
+		}
+	}
+	fmt.Fprintln(output)
+}